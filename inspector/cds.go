@@ -0,0 +1,197 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Checks the CDS and CDNSKEY RRsets a child zone publishes to update its DS
+// RRset at the parent (RFC 7344, RFC 8078) and reports the DS RRset the
+// parent should publish.
+func (in *Inspector) checkCDS(ctx context.Context, z *Zone) {
+	if z.FQDN == "." {
+		return
+	}
+	m := in.dnssecQuery(ctx, z.FQDN, dns.TypeCDS, in.auth)
+	cds, cdsSigs := rrsetOf(m, z.FQDN, dns.TypeCDS), getRRsigs(m, dns.TypeCDS)
+	m = in.dnssecQuery(ctx, z.FQDN, dns.TypeCDNSKEY, in.auth)
+	cdnskey, cdnskeySigs := rrsetOf(m, z.FQDN, dns.TypeCDNSKEY), getRRsigs(m, dns.TypeCDNSKEY)
+	if len(cds) == 0 && len(cdnskey) == 0 {
+		return
+	}
+	keys, _ := in.getDNSKEYset(ctx, z.FQDN)
+	ds, _, _, _ := in.getDSset(ctx, z.FQDN)
+	// Keys of the current chain of trust: matched by the DS RRs at the
+	// parent or by a trust anchor
+	var trusted []*dns.DNSKEY
+	for _, k := range keys {
+		if isAnchored(k, ds) || isAnchored(k, in.anchorsFor(z.FQDN)) {
+			trusted = append(trusted, k)
+		}
+	}
+	c := evaluateCDS(cds, cdsSigs, cdnskey, cdnskeySigs, keys, trusted, ds, in.now())
+	sets := map[string]bool{}
+	for _, n := range z.AutoritativeNS {
+		for _, a := range n.Addresses {
+			if a.Reachable {
+				sets[fmt.Sprint(a.CDS, a.CDNSKEY)] = true
+			}
+		}
+	}
+	if len(sets) > 1 {
+		c.Issues = append(c.Issues, "the authoritative nameservers serve different CDS/CDNSKEY RRsets")
+		c.Consistent, c.Accepted, c.UpdateNeeded, c.DS = false, false, false, nil
+	}
+	z.CDS = c
+}
+
+// Evaluates CDS and CDNSKEY RRsets by RFC 7344 section 4.1 and RFC 8078:
+//   - both RRsets have to be signed by a key of the current chain of trust
+//   - if both are published they have to describe the same keys
+//   - every key has to be in the DNSKEY RRset
+//   - the delete signal (RFC 8078 section 4) has to be the only RR of its set
+//
+// If the RRsets are accepted the DS RRset the parent should publish is
+// derived from them; the delete signal yields no DS RRs.
+func evaluateCDS(cds []dns.RR, cdsSigs []*dns.RRSIG, cdnskey []dns.RR, cdnskeySigs []*dns.RRSIG,
+	keys, trusted []*dns.DNSKEY, ds []dns.RR, now time.Time) *CDS {
+	c := &CDS{Signed: true, Consistent: true}
+	var cdsRRs []*dns.CDS
+	var cdnskeyRRs []*dns.CDNSKEY
+	deleteCDS, deleteCDNSKEY := false, false
+	for _, rr := range cds {
+		d := rr.(*dns.CDS)
+		cdsRRs = append(cdsRRs, d)
+		c.CDS = append(c.CDS, rdataString(d))
+		if d.Algorithm == 0 && d.DigestType == 0 && strings.Trim(d.Digest, "0") == "" {
+			deleteCDS = true
+		}
+	}
+	for _, rr := range cdnskey {
+		k := rr.(*dns.CDNSKEY)
+		cdnskeyRRs = append(cdnskeyRRs, k)
+		c.CDNSKEY = append(c.CDNSKEY, rdataString(k))
+		if k.Algorithm == 0 && k.Flags == 0 {
+			deleteCDNSKEY = true
+		}
+	}
+
+	if len(trusted) == 0 {
+		c.Signed = false
+		c.Issues = append(c.Issues, "the zone has no key matched by a DS RR, the DS RRs have to be bootstrapped (RFC 8078 section 3)")
+	} else {
+		if err := verifyRRset(cds, cdsSigs, trusted, now); len(cds) > 0 && err != nil {
+			c.Signed = false
+			c.Issues = append(c.Issues, fmt.Sprintf("CDS RRset is not signed by a key of the chain of trust: %s", err))
+		}
+		if err := verifyRRset(cdnskey, cdnskeySigs, trusted, now); len(cdnskey) > 0 && err != nil {
+			c.Signed = false
+			c.Issues = append(c.Issues, fmt.Sprintf("CDNSKEY RRset is not signed by a key of the chain of trust: %s", err))
+		}
+	}
+
+	var want []*dns.DS
+	switch {
+	case len(cds) > 0 && len(cdnskey) > 0 && deleteCDS != deleteCDNSKEY:
+		c.Consistent = false
+		c.Issues = append(c.Issues, "only one of the CDS and CDNSKEY RRsets signals to delete the DS RRs")
+	case deleteCDS || deleteCDNSKEY:
+		c.Delete = true
+		if len(cds) > 1 || len(cdnskey) > 1 {
+			c.Issues = append(c.Issues, "the delete signal has to be the only CDS/CDNSKEY RR")
+		}
+	default:
+		for _, d := range cdsRRs {
+			want = append(want, cdsToDS(d))
+			if findKey(keys, d.KeyTag, d.Algorithm) == nil {
+				c.Issues = append(c.Issues, fmt.Sprintf("CDS RR %d matches no DNSKEY", d.KeyTag))
+			}
+			if len(cdnskeyRRs) > 0 && !cdsHasCDNSKEY(d, cdnskeyRRs) {
+				c.Consistent = false
+				c.Issues = append(c.Issues, fmt.Sprintf("CDS RR %d has no matching CDNSKEY RR", d.KeyTag))
+			}
+		}
+		for _, k := range cdnskeyRRs {
+			key := cdnskeyToDNSKEY(k)
+			if findKey(keys, key.KeyTag(), key.Algorithm) == nil {
+				c.Issues = append(c.Issues, fmt.Sprintf("CDNSKEY RR %d is not in the DNSKEY RRset", key.KeyTag()))
+			}
+			if len(cdsRRs) == 0 {
+				want = append(want, key.ToDS(dns.SHA256))
+			} else if !cdnskeyHasCDS(key, cdsRRs) {
+				c.Consistent = false
+				c.Issues = append(c.Issues, fmt.Sprintf("CDNSKEY RR %d has no matching CDS RR", key.KeyTag()))
+			}
+		}
+	}
+
+	c.Accepted = len(c.Issues) == 0
+	if !c.Accepted {
+		return c
+	}
+	current := map[string]bool{}
+	for _, rr := range ds {
+		current[rdataString(rr)] = true
+	}
+	for _, d := range want {
+		c.DS = append(c.DS, d.String())
+		if !current[rdataString(d)] {
+			c.UpdateNeeded = true
+		}
+	}
+	if len(want) != len(current) {
+		c.UpdateNeeded = true
+	}
+	return c
+}
+
+func cdsHasCDNSKEY(d *dns.CDS, cdnskey []*dns.CDNSKEY) bool {
+	for _, k := range cdnskey {
+		if dsMatchesKey(cdsToDS(d), cdnskeyToDNSKEY(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+func cdnskeyHasCDS(key *dns.DNSKEY, cds []*dns.CDS) bool {
+	for _, d := range cds {
+		if dsMatchesKey(cdsToDS(d), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the DS RR a CDS RR asks for
+func cdsToDS(d *dns.CDS) *dns.DS {
+	ds := d.DS
+	ds.Hdr.Rrtype = dns.TypeDS
+	return &ds
+}
+
+// Returns the DNSKEY RR a CDNSKEY RR refers to
+func cdnskeyToDNSKEY(k *dns.CDNSKEY) *dns.DNSKEY {
+	key := k.DNSKEY
+	key.Hdr.Rrtype = dns.TypeDNSKEY
+	return &key
+}
+
+// Returns the RRs of type t owned by name from the answer section
+func rrsetOf(m dns.Msg, name string, t uint16) (ret []dns.RR) {
+	for _, rr := range m.Answer {
+		if rr.Header().Rrtype == t && equalNames(rr.Header().Name, name) {
+			ret = append(ret, rr)
+		}
+	}
+	return
+}
+
+// Returns the RDATA of an RR in presentation format
+func rdataString(rr dns.RR) string {
+	return strings.TrimSpace(strings.TrimPrefix(rr.String(), rr.Header().String()))
+}
@@ -0,0 +1,73 @@
+package inspector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestEvaluateCDS(t *testing.T) {
+	ksk := newTestZone(t, "example.")
+	newKSK := newTestZone(t, "example.")
+	keys := []*dns.DNSKEY{ksk.key, newKSK.key}
+	current := []dns.RR{ksk.key.ToDS(dns.SHA256)}
+
+	cdsFor := func(z *testZone) dns.RR {
+		c := &dns.CDS{DS: *z.key.ToDS(dns.SHA256)}
+		c.Hdr.Rrtype = dns.TypeCDS
+		return c
+	}
+	cdnskeyFor := func(z *testZone) dns.RR {
+		c := &dns.CDNSKEY{DNSKEY: *z.key}
+		c.Hdr.Rrtype = dns.TypeCDNSKEY
+		return c
+	}
+	signed := func(rrs ...dns.RR) ([]dns.RR, []*dns.RRSIG) {
+		return rrs, []*dns.RRSIG{ksk.sign(t, rrs...)}
+	}
+	now := time.Now()
+
+	// Rollover to the new KSK
+	cds, cdsSigs := signed(cdsFor(ksk), cdsFor(newKSK))
+	cdnskey, cdnskeySigs := signed(cdnskeyFor(ksk), cdnskeyFor(newKSK))
+	c := evaluateCDS(cds, cdsSigs, cdnskey, cdnskeySigs, keys, keys[:1], current, now)
+	if !c.Accepted || !c.Signed || !c.Consistent || c.Delete || !c.UpdateNeeded || len(c.DS) != 2 {
+		t.Errorf("rollover: got %+v", c)
+	}
+
+	// CDS and CDNSKEY RRsets describing different keys
+	cdnskey, cdnskeySigs = signed(cdnskeyFor(ksk))
+	c = evaluateCDS(cds, cdsSigs, cdnskey, cdnskeySigs, keys, keys[:1], current, now)
+	if c.Accepted || c.Consistent || len(c.DS) != 0 {
+		t.Errorf("inconsistent: got %+v", c)
+	}
+
+	// Signed by a key that is not matched by the DS RRset
+	cds, cdsSigs = []dns.RR{cdsFor(newKSK)}, []*dns.RRSIG{newKSK.sign(t, cdsFor(newKSK))}
+	c = evaluateCDS(cds, cdsSigs, nil, nil, keys, keys[:1], current, now)
+	if c.Accepted || c.Signed {
+		t.Errorf("not signed by the chain: got %+v", c)
+	}
+
+	// Unchanged DS RRset
+	cds, cdsSigs = signed(cdsFor(ksk))
+	c = evaluateCDS(cds, cdsSigs, nil, nil, keys, keys[:1], current, now)
+	if !c.Accepted || c.UpdateNeeded {
+		t.Errorf("unchanged: got %+v", c)
+	}
+
+	// Delete signal of RFC 8078
+	cds, cdsSigs = signed(mustRRs(t, "example. 3600 IN CDS 0 0 0 00")...)
+	cdnskey, cdnskeySigs = signed(mustRRs(t, "example. 3600 IN CDNSKEY 0 3 0 AA==")...)
+	c = evaluateCDS(cds, cdsSigs, cdnskey, cdnskeySigs, keys, keys[:1], current, now)
+	if !c.Accepted || !c.Delete || !c.UpdateNeeded || len(c.DS) != 0 {
+		t.Errorf("delete: got %+v", c)
+	}
+
+	// No DS RRs yet: bootstrapping
+	c = evaluateCDS(cds, cdsSigs, nil, nil, keys, nil, nil, now)
+	if c.Accepted || c.Signed || len(c.Issues) != 1 {
+		t.Errorf("bootstrapping: got %+v", c)
+	}
+}
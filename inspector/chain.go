@@ -0,0 +1,239 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Security status of a zone as defined in RFC 4035 section 4.3
+const (
+	StatusSecure        = "secure"
+	StatusInsecure      = "insecure"
+	StatusBogus         = "bogus"
+	StatusIndeterminate = "indeterminate"
+)
+
+// Validates the chain of trust from the configured trust anchors down to the
+// target. res.Zones is ordered from the target up to the root, so the zones
+// are processed in reverse order. A zone is
+//   - secure if its DNSKEY RRset is signed by a key that is matched either by
+//     a trust anchor or by a validated DS RR of the secure parent zone
+//   - insecure if the parent is insecure, proves that there is no DS RR for
+//     the zone or has no DS RR with a supported algorithm for it
+//   - bogus if a signature along the chain is missing or does not validate,
+//     or if the DS RRs are missing without a signed proof of their absence
+//   - indeterminate if no trust anchor is configured above it or if the DS
+//     RRset cannot be looked up in its parent
+//
+// A secure target zone whose own RRsets fail validation (see
+// checkRRValidation) is bogus as well. The RRsets of the zones above the
+// target do not affect the status, they are not part of the chain of trust.
+//
+// The lowest zone with a self-signed DNSKEY RRset but no DS RR in its secure
+// parent is reported as trust island, whether the delegation is provably
+// insecure or not. The DS RRs for its KSKs are recommended to the parent.
+func (in *Inspector) validateChain(ctx context.Context, res *Result) {
+	status := StatusIndeterminate
+	var parentKeys []*dns.DNSKEY
+	for i := len(res.Zones) - 1; i >= 0; i-- {
+		z := &res.Zones[i]
+		keys, keySigs := in.getDNSKEYset(ctx, z.FQDN)
+		var err error
+		if anchors := in.anchorsFor(z.FQDN); len(anchors) > 0 {
+			status, err = validateDNSKEYs(keys, keySigs, anchors, in.now())
+		} else if status == StatusSecure {
+			ds, dsSigs, dsNs, dsErr := in.getDSset(ctx, z.FQDN)
+			if dsErr != nil {
+				status, err = StatusIndeterminate, dsErr
+			} else if len(ds) == 0 {
+				status, err = validateNoDS(z.FQDN, dsNs, parentKeys, in.now())
+			} else if err = verifyRRset(ds, dsSigs, parentKeys, in.now()); err != nil {
+				status, err = StatusBogus, fmt.Errorf("DS RRset: %s", err)
+			} else if !supportedDS(ds) {
+				status, err = StatusInsecure, errors.New("no DS RR with a supported algorithm")
+			} else {
+				status, err = validateDNSKEYs(keys, keySigs, ds, in.now())
+			}
+			if dsErr == nil && len(ds) == 0 && len(keys) > 0 && validateSelfSigned(keys, keySigs, in.now()) == nil {
+				res.TrustIsland = true
+				res.TrustIslandAnchorZone = z.FQDN
+				res.RecommendedDS = dsRecords(signingKSKs(keys, keySigs))
+			}
+		} else if status != StatusIndeterminate {
+			err = fmt.Errorf("parent zone is %s", status)
+		}
+		z.Status = status
+		if err != nil {
+			z.StatusReason = err.Error()
+		}
+		parentKeys = nil
+		if status == StatusSecure {
+			parentKeys = keys
+		}
+	}
+	if len(res.Zones) > 0 {
+		target := &res.Zones[0]
+		if err := target.rrsetError(); target.Status == StatusSecure && err != nil {
+			target.Status, target.StatusReason = StatusBogus, err.Error()
+		}
+		res.Status = target.Status
+	}
+}
+
+// Returns the first validation error of the RRsets of the zone
+func (z *Zone) rrsetError() error {
+	for _, e := range []struct{ section, err string }{
+		{"answer", z.ValidationErrorAnswer},
+		{"authority", z.ValidationErrorNs},
+		{"additional", z.ValidationErrorExtra},
+	} {
+		if e.err != "" {
+			return fmt.Errorf("RRset in the %s section does not validate: %s", e.section, e.err)
+		}
+	}
+	return nil
+}
+
+// Distinguishes a provably insecure delegation from a missing or stripped DS
+// RRset. The absence of the DS RRs has to be proven by NSEC/NSEC3 RRs signed
+// by the keys of the secure parent zone.
+func validateNoDS(zone string, ns []dns.RR, parentKeys []*dns.DNSKEY, now time.Time) (string, error) {
+	if err := verifyDenialRRs(&Denial{}, ns, parentKeys, now); err != nil {
+		return StatusBogus, fmt.Errorf("no DS RR and no valid proof of its absence: %s", err)
+	}
+	if err := proveNoDS(zone, ns); err != nil {
+		return StatusBogus, fmt.Errorf("no DS RR and no valid proof of its absence: %s", err)
+	}
+	return StatusInsecure, errors.New("provably insecure delegation: the parent zone proves that there is no DS RR")
+}
+
+// Validates a DNSKEY RRset against a set of DS or DNSKEY RRs it must be anchored to
+func validateDNSKEYs(keys []*dns.DNSKEY, sigs []*dns.RRSIG, anchors []dns.RR, now time.Time) (string, error) {
+	if len(keys) == 0 {
+		return StatusBogus, errors.New("no DNSKEY RRs in the zone")
+	}
+	var trusted []*dns.DNSKEY
+	for _, k := range keys {
+		if isAnchored(k, anchors) {
+			trusted = append(trusted, k)
+		}
+	}
+	if len(trusted) == 0 {
+		return StatusBogus, errors.New("no DNSKEY matches the DS RRs / trust anchor")
+	}
+	if err := verifyRRset(keysToRRs(keys), sigs, trusted, now); err != nil {
+		return StatusBogus, fmt.Errorf("DNSKEY RRset: %s", err)
+	}
+	return StatusSecure, nil
+}
+
+// Checks if the DNSKEY RRset is signed by a KSK of its own
+func validateSelfSigned(keys []*dns.DNSKEY, sigs []*dns.RRSIG, now time.Time) error {
+	var ksks []*dns.DNSKEY
+	for _, k := range keys {
+		if k.Flags&dns.SEP != 0 {
+			ksks = append(ksks, k)
+		}
+	}
+	return verifyRRset(keysToRRs(keys), sigs, ksks, now)
+}
+
+// Returns the KSKs signing the DNSKEY RRset, without revoked keys
+func signingKSKs(keys []*dns.DNSKEY, sigs []*dns.RRSIG) (ret []*dns.DNSKEY) {
+	for _, k := range keys {
+		if k.Flags&dns.SEP != 0 && k.Flags&dns.REVOKE == 0 && signedBy(sigs, k) {
+			ret = append(ret, k)
+		}
+	}
+	return
+}
+
+// Verifies that at least one RRSIG over rrset is made by one of the keys and
+// is within its validity period at now.
+func verifyRRset(rrset []dns.RR, sigs []*dns.RRSIG, keys []*dns.DNSKEY, now time.Time) error {
+	if len(sigs) == 0 {
+		return errors.New("no RRSIG RR")
+	}
+	err := errors.New("no RRSIG made by a trusted key")
+	for _, sig := range sigs {
+		for _, k := range keys {
+			if k.KeyTag() != sig.KeyTag || k.Algorithm != sig.Algorithm || !equalNames(k.Hdr.Name, sig.SignerName) {
+				continue
+			}
+			if !sig.ValidityPeriod(now) {
+				err = &validationError{sig, "The validity period expired"}
+				continue
+			}
+			if e := sig.Verify(k, rrset); e != nil {
+				err = &validationError{sig, fmt.Sprintf("Cannot validate the siganture cryptographically: %s", e)}
+				continue
+			}
+			return nil
+		}
+	}
+	return err
+}
+
+// Checks if at least one DS RR uses a digest and key algorithm this package can validate
+func supportedDS(ds []dns.RR) bool {
+	for _, rr := range ds {
+		d, ok := rr.(*dns.DS)
+		if !ok {
+			continue
+		}
+		switch d.DigestType {
+		case dns.SHA1, dns.SHA256, dns.SHA384:
+		default:
+			continue
+		}
+		switch d.Algorithm {
+		case dns.RSASHA1, dns.RSASHA1NSEC3SHA1, dns.RSASHA256, dns.RSASHA512,
+			dns.ECDSAP256SHA256, dns.ECDSAP384SHA384, dns.ED25519:
+			return true
+		}
+	}
+	return false
+}
+
+// Gets the DNSKEY RRset of a zone and the RRSIGs covering it
+func (in *Inspector) getDNSKEYset(ctx context.Context, fqdn string) (keys []*dns.DNSKEY, sigs []*dns.RRSIG) {
+	m := in.dnssecQuery(ctx, fqdn, dns.TypeDNSKEY, in.resolver)
+	for _, r := range m.Answer {
+		if k, ok := r.(*dns.DNSKEY); ok && equalNames(k.Hdr.Name, fqdn) {
+			keys = append(keys, k)
+		}
+	}
+	sigs = getRRsigs(m, dns.TypeDNSKEY)
+	return
+}
+
+// Gets the DS RRset of a zone from its parent and the RRSIGs covering it. The
+// authority section is returned to prove the absence of DS RRs. An error is
+// returned if the lookup failed, e.g. because no server answered.
+func (in *Inspector) getDSset(ctx context.Context, fqdn string) (ds []dns.RR, sigs []*dns.RRSIG, ns []dns.RR, err error) {
+	m := in.dnssecQuery(ctx, fqdn, dns.TypeDS, in.resolver)
+	if m.Rcode != dns.RcodeSuccess && m.Rcode != dns.RcodeNameError {
+		err = fmt.Errorf("DS lookup failed: %s", dns.RcodeToString[m.Rcode])
+		return
+	}
+	for _, r := range m.Answer {
+		if d, ok := r.(*dns.DS); ok && equalNames(d.Hdr.Name, fqdn) {
+			ds = append(ds, d)
+		}
+	}
+	sigs = getRRsigs(m, dns.TypeDS)
+	ns = m.Ns
+	return
+}
+
+func keysToRRs(keys []*dns.DNSKEY) []dns.RR {
+	ret := make([]dns.RR, len(keys))
+	for i, k := range keys {
+		ret[i] = k
+	}
+	return ret
+}
@@ -0,0 +1,231 @@
+package inspector
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// testZone is a signed zone with a single key used as KSK and ZSK
+type testZone struct {
+	key  *dns.DNSKEY
+	priv crypto.Signer
+}
+
+func newTestZone(t *testing.T, name string) *testZone {
+	key := &dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: name, Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     KSK,
+		Protocol:  3,
+		Algorithm: dns.ECDSAP256SHA256,
+	}
+	// RRSIG.Sign rejects key tag 0
+	priv, err := key.Generate(256)
+	for err == nil && key.KeyTag() == 0 {
+		priv, err = key.Generate(256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testZone{key: key, priv: priv.(crypto.Signer)}
+}
+
+// Signs rrset with the key of the zone
+func (z *testZone) sign(t *testing.T, rrset ...dns.RR) *dns.RRSIG {
+	sig := &dns.RRSIG{
+		Hdr:        dns.RR_Header{Name: rrset[0].Header().Name, Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: rrset[0].Header().Ttl},
+		KeyTag:     z.key.KeyTag(),
+		SignerName: z.key.Hdr.Name,
+		Algorithm:  z.key.Algorithm,
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(24 * time.Hour).Unix()),
+	}
+	if err := sig.Sign(z.priv, rrset); err != nil {
+		t.Fatal(err)
+	}
+	return sig
+}
+
+// Returns the DNSKEY RRset of the zone with its signature
+func (z *testZone) keyset(t *testing.T) []dns.RR {
+	return []dns.RR{z.key, z.sign(t, z.key)}
+}
+
+// Returns the DS RR for child signed by z
+func (z *testZone) delegate(t *testing.T, child *testZone) []dns.RR {
+	ds := child.key.ToDS(dns.SHA256)
+	ds.Hdr.Ttl = 3600
+	return []dns.RR{ds, z.sign(t, ds)}
+}
+
+func newChainResult(zones ...string) *Result {
+	res := &Result{}
+	for _, z := range zones {
+		res.Zones = append(res.Zones, Zone{FQDN: z})
+	}
+	return res
+}
+
+func TestValidateChain(t *testing.T) {
+	root := newTestZone(t, ".")
+	example := newTestZone(t, "example.")
+	anchor := root.key.ToDS(dns.SHA256)
+
+	var rrs []dns.RR
+	rrs = append(rrs, root.keyset(t)...)
+	rrs = append(rrs, example.keyset(t)...)
+	rrs = append(rrs, root.delegate(t, example)...)
+	in := New(Options{Resolver: &fakeResolver{rrs: rrs}, TrustAnchors: []dns.RR{anchor}})
+
+	res := newChainResult("example", ".")
+	in.validateChain(context.Background(), res)
+	for _, z := range res.Zones {
+		if z.Status != StatusSecure {
+			t.Errorf("Zone %s is %s (%s), want secure", z.FQDN, z.Status, z.StatusReason)
+		}
+	}
+	if res.Status != StatusSecure || res.TrustIsland {
+		t.Errorf("Unexpected result %+v", res)
+	}
+
+	// A spoofed root does not match the configured anchor
+	spoofed := newTestZone(t, ".")
+	in = New(Options{Resolver: &fakeResolver{rrs: rrs}, TrustAnchors: []dns.RR{spoofed.key.ToDS(dns.SHA256)}})
+	res = newChainResult("example", ".")
+	in.validateChain(context.Background(), res)
+	if res.Zones[1].Status != StatusBogus || res.Status != StatusBogus {
+		t.Errorf("Spoofed root validates: %+v", res.Zones)
+	}
+}
+
+func TestValidateChainWithoutDS(t *testing.T) {
+	root := newTestZone(t, ".")
+	island := newTestZone(t, "island.")
+	var rrs []dns.RR
+	rrs = append(rrs, root.keyset(t)...)
+	rrs = append(rrs, island.keyset(t)...)
+	fake := &fakeResolver{rrs: rrs}
+	in := New(Options{Resolver: fake, TrustAnchors: []dns.RR{root.key}})
+
+	// Missing DS without proof looks like a stripped DS
+	res := newChainResult("www.island", "island", ".")
+	in.validateChain(context.Background(), res)
+	want := []string{StatusBogus, StatusBogus, StatusSecure}
+	for i, z := range res.Zones {
+		if z.Status != want[i] {
+			t.Errorf("Zone %s is %s, want %s", z.FQDN, z.Status, want[i])
+		}
+	}
+	if !res.TrustIsland || res.TrustIslandAnchorZone != "island" {
+		t.Errorf("Trust island not detected: %+v", res)
+	}
+	if len(res.RecommendedDS) != 2 || res.RecommendedDS[0] != island.key.ToDS(dns.SHA256).String() {
+		t.Errorf("Recommended DS RRs %q, want SHA-256 and SHA-384 DS of %d", res.RecommendedDS, island.key.KeyTag())
+	}
+
+	// Signed NSEC RR proves the insecure delegation
+	nsec := mustRRs(t, "island. 86400 IN NSEC zz. NS RRSIG NSEC")[0]
+	fake.negative = []dns.RR{nsec, root.sign(t, nsec)}
+	res = newChainResult("www.island", "island", ".")
+	in.validateChain(context.Background(), res)
+	want = []string{StatusInsecure, StatusInsecure, StatusSecure}
+	for i, z := range res.Zones {
+		if z.Status != want[i] {
+			t.Errorf("Zone %s is %s (%s), want %s", z.FQDN, z.Status, z.StatusReason, want[i])
+		}
+	}
+}
+
+func TestParseXMLTrustAnchors(t *testing.T) {
+	data := []byte(`<?xml version="1.0" encoding="UTF-8"?>
+<TrustAnchor id="E9724F53-1851-4F86-85E5-F1392102940B" source="http://data.iana.org/root-anchors/root-anchors.xml">
+<Zone>.</Zone>
+<KeyDigest id="Kjqmt7v" validFrom="2010-07-15T00:00:00+00:00" validUntil="2019-01-11T00:00:00+00:00">
+<KeyTag>19036</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>49AAC11D7B6F6446702E54A1607371607A1A41855200FD2CE1CDDE32F24E8FB5</Digest>
+</KeyDigest>
+<KeyDigest id="Klajeyz" validFrom="2017-02-02T00:00:00+00:00">
+<KeyTag>20326</KeyTag>
+<Algorithm>8</Algorithm>
+<DigestType>2</DigestType>
+<Digest>E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D</Digest>
+</KeyDigest>
+</TrustAnchor>`)
+	rrs, err := parseXMLTrustAnchors(data, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rrs) != 1 || rrs[0].(*dns.DS).KeyTag != 20326 || rrs[0].Header().Name != "." {
+		t.Errorf("Unexpected trust anchors: %v", rrs)
+	}
+	if len(rootAnchors()) != len(RootAnchors) {
+		t.Error("Cannot parse built-in root anchors")
+	}
+}
+
+func TestVerifyRRsetAt(t *testing.T) {
+	z := newTestZone(t, "example.")
+	sig := z.sign(t, z.key)
+	for _, tt := range []struct {
+		at    time.Time
+		valid bool
+	}{
+		{time.Now(), true},
+		{time.Now().Add(-2 * time.Hour), false},
+		{time.Now().Add(48 * time.Hour), false},
+	} {
+		err := verifyRRset([]dns.RR{z.key}, []*dns.RRSIG{sig}, []*dns.DNSKEY{z.key}, tt.at)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: got %v, want valid=%t", tt.at, err, tt.valid)
+		}
+	}
+}
+
+// Answers SERVFAIL for DS queries as an unreachable parent does
+type dsFailResolver struct{ Resolver }
+
+func (f dsFailResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	if m.Question[0].Qtype == dns.TypeDS {
+		return nil, errors.New("connection refused")
+	}
+	return f.Resolver.Exchange(ctx, m)
+}
+
+func TestValidateChainFailedDSLookup(t *testing.T) {
+	root := newTestZone(t, ".")
+	example := newTestZone(t, "example.")
+	var rrs []dns.RR
+	rrs = append(rrs, root.keyset(t)...)
+	rrs = append(rrs, example.keyset(t)...)
+	in := New(Options{Resolver: dsFailResolver{&fakeResolver{rrs: rrs}}, TrustAnchors: []dns.RR{root.key}})
+
+	res := newChainResult("www.example", "example", ".")
+	in.validateChain(context.Background(), res)
+	want := []string{StatusIndeterminate, StatusIndeterminate, StatusSecure}
+	for i, z := range res.Zones {
+		if z.Status != want[i] {
+			t.Errorf("Zone %s is %s (%s), want %s", z.FQDN, z.Status, z.StatusReason, want[i])
+		}
+	}
+	if res.Zones[1].StatusReason != "DS lookup failed: SERVFAIL" || res.TrustIsland {
+		t.Errorf("Unexpected result %+v", res)
+	}
+}
+
+func TestValidateChainTargetRRsets(t *testing.T) {
+	root := newTestZone(t, ".")
+	in := New(Options{Resolver: &fakeResolver{rrs: root.keyset(t)}, TrustAnchors: []dns.RR{root.key}})
+
+	res := newChainResult(".")
+	res.Zones[0].ValidationErrorAnswer = "The validity period expired"
+	in.validateChain(context.Background(), res)
+	if res.Status != StatusBogus || res.Zones[0].StatusReason != "RRset in the answer section does not validate: The validity period expired" {
+		t.Errorf("Unexpected result %s (%s)", res.Status, res.Zones[0].StatusReason)
+	}
+}
@@ -0,0 +1,125 @@
+package inspector
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/miekg/dns"
+)
+
+/* Parses a given RSA key as base64 encoded string and returns the
+key material and the bit length of the key as single values (e, n, KeyLength).
+The format is defined in RFC 3110 section 2.
+*/
+func parseRSA(key string) (big.Int, big.Int, int, error) {
+	keyBinary, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return big.Int{}, big.Int{}, 0, fmt.Errorf("RSA key is not base64 readable: %s", err)
+	}
+	var e, n *big.Int
+	var el, l int
+	switch {
+	case len(keyBinary) == 0:
+		return big.Int{}, big.Int{}, 0, errors.New("RSA key is empty")
+	case keyBinary[0] == 0:
+		if len(keyBinary) < 3 {
+			return big.Int{}, big.Int{}, 0, errors.New("RSA key is truncated")
+		}
+		el = (int(keyBinary[1]) << 8) + int(keyBinary[2])
+		if len(keyBinary) <= el+3 {
+			return big.Int{}, big.Int{}, 0, errors.New("RSA key has no modulus")
+		}
+		e = new(big.Int).SetBytes(keyBinary[3 : el+3])
+		n = new(big.Int).SetBytes(keyBinary[el+3:])
+		l = len(keyBinary[el+3:]) * 8
+	default:
+		el = int(keyBinary[0])
+		if len(keyBinary) <= el+1 {
+			return big.Int{}, big.Int{}, 0, errors.New("RSA key has no modulus")
+		}
+		e = new(big.Int).SetBytes(keyBinary[1 : el+1])
+		n = new(big.Int).SetBytes(keyBinary[el+1:])
+		l = len(keyBinary[el+1:]) * 8
+	}
+	return *e, *n, l, nil
+}
+
+/* Parses an given DSA key as base64 encoded string and returns the
+key material as single values. The format is defined in RFC 2536 section 2.
+*/
+func parseDSA(key string) (big.Int, big.Int, big.Int, big.Int, int, error) {
+	var z big.Int
+	keyBinary, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return z, z, z, z, 0, fmt.Errorf("DSA key is not base64 readable: %s", err)
+	}
+	if len(keyBinary) == 0 {
+		return z, z, z, z, 0, errors.New("DSA key is empty")
+	}
+	t := int(keyBinary[0])
+	if len(keyBinary) < 21+(64+t*8)*3 {
+		return z, z, z, z, 0, errors.New("DSA key is truncated")
+	}
+	q := new(big.Int).SetBytes(keyBinary[1:21])
+	p := new(big.Int).SetBytes(keyBinary[21 : 21+(64+t*8)])
+	g := new(big.Int).SetBytes(keyBinary[21+(64+t*8) : 21+(64+t*8)*2])
+	y := new(big.Int).SetBytes(keyBinary[21+(64+t*8)*2:])
+	l := p.BitLen()
+	return *q, *p, *g, *y, l, nil
+}
+
+/* Evaluates a DNSKEY RR against the rules of the compliance policies. The
+verdict of every policy knowing the algorithm is appended to k.Compliance,
+the first one also fills the verdict fields of k. The key length of RSA and
+DSA keys is read from the key material, other algorithms have a fixed key
+length given by the policy. Rules whose until year is before year are
+NON-COMPLIANT. Malformed key material is reported in k.Error.
+*/
+func checkKey(keyRR dns.DNSKEY, k *Key, policies []*Policy, year int) {
+	var err error
+	if keyRR.Protocol == 3 {
+		if keyRR.Flags&^dns.REVOKE == ZSK {
+			k.Type = "ZSK"
+		} else if keyRR.Flags&^dns.REVOKE == KSK {
+			k.Type = "KSK"
+		}
+		k.Revoked = keyRR.Flags&dns.REVOKE != 0
+		for _, p := range policies {
+			rule := p.algorithm(keyRR.Algorithm)
+			if rule == nil {
+				continue
+			}
+			if k.Alg == "" {
+				k.Alg = rule.Alg
+				switch {
+				case rule.KeyLength > 0:
+					k.KeyLength = rule.KeyLength
+				case rule.Alg == "RSA":
+					_, _, k.KeyLength, err = parseRSA(keyRR.PublicKey)
+				case rule.Alg == "DSA":
+					_, _, _, _, k.KeyLength, err = parseDSA(keyRR.PublicKey)
+				}
+				if err != nil {
+					k.Error = err.Error()
+				}
+			}
+			c := Compliance{Policy: p.String()}
+			c.AComment, c.AUntil = rule.keySize(k.KeyLength, year)
+			if rule.Hash != "" {
+				c.HComment, c.HUntil = p.hash(rule.Hash, year)
+				if k.Hash == "" {
+					k.Hash = rule.Hash
+				}
+			}
+			if k.Policy == "" {
+				k.Policy = c.Policy
+				k.AComment, k.AUntil = c.AComment, c.AUntil
+				k.HComment, k.HUntil = c.HComment, c.HUntil
+			}
+			k.Compliance = append(k.Compliance, c)
+		}
+	}
+	return
+}
@@ -0,0 +1,304 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+const (
+	// ZSK has the id 256 in DNSKEY RR
+	ZSK uint16 = 256
+	// KSK has the id 257 in DNSKEY RR
+	KSK uint16 = 257
+)
+
+type noDSerror struct {
+	key dns.DNSKEY
+	msg string
+}
+
+func (e *noDSerror) Error() string {
+	return fmt.Sprintf("%v\n -- Error: %s", e.key.String(), e.msg)
+}
+
+/* The function decomposes the original fqdn into the names from the target up
+to the root and detects which of them are the apex of a zone (see findZoneCuts).
+e.g. www.corporate-trust.de. yields the zones {"corporate-trust.de", "de", "."}
+while "www.corporate-trust.de" is only a label inside corporate-trust.de.
+For each zone the function checks...
+	* the existance and compliance of DNSKEY-RRs with the configured policies (BSI by default)
+	* the DS RRs at the parent zone and the CDS/CDNSKEY RRs to update them
+	* running key rollovers
+	* the existance of NSEC3-RRs and their parameters (RFC 9276)
+	* the authenticated denial of existence (NSEC/NSEC3 proofs) and how far it
+	  exposes the names of the zone
+	* the validation of RRs and the validity periods of their signatures
+	* the consistency of the authoritative nameservers
+	* the response sizes and the risk of IP fragmentation (DNS Flag Day 2020)
+Afterwards the chain of trust is validated from the trust anchor down to the
+target (see validateChain).
+*/
+func (in *Inspector) checkPath(ctx context.Context, res *Result, fqdn string) {
+	res.Path = in.findZoneCuts(ctx, pathNames(fqdn))
+	var zoneList []string
+	for _, p := range res.Path {
+		if p.ZoneCut {
+			zoneList = append(zoneList, p.Name)
+		}
+	}
+	for _, fqdn = range zoneList {
+		if ctx.Err() != nil {
+			break
+		}
+		z := &Zone{}
+		z.FQDN = fqdn
+		in.checkZone(ctx, z)
+		res.Zones = append(res.Zones, *z)
+	}
+	in.validateChain(ctx, res)
+	return
+}
+
+/* Runs the checks of a single zone */
+func (in *Inspector) checkZone(ctx context.Context, z *Zone) {
+	z.probe = in.probeName(ctx, z.FQDN)
+	z.AutoritativeNS = in.checkAuthNS(ctx, z.FQDN)
+	for i := range z.AutoritativeNS {
+		in.checkAddresses(ctx, &z.AutoritativeNS[i], z.FQDN)
+	}
+	checkConsistency(z)
+	in.checkResponseSizes(ctx, z)
+	in.checkNSEC3Existence(ctx, z, z.FQDN)
+	in.checkDenialOfExistence(ctx, z)
+	in.checkEnumeration(ctx, z)
+	in.checkNSEC3Parameters(ctx, z)
+	// Check signed sections (includes checking the validation of ZSK)
+	in.checkRRValidation(ctx, z.FQDN, z)
+	in.checkSignatures(ctx, z)
+	zskValidity := in.checkZSKverifiability(ctx, z.FQDN)
+	m := in.dnssecQuery(ctx, z.FQDN, dns.TypeDNSKEY, in.resolver)
+	keys := getDNSKEYs(m, ZSK)
+	keyRes1 := make([]Key, len(keys))
+	for i, k := range keys {
+		checkKey(k, &keyRes1[i], in.policies, in.now().Year())
+		keyRes1[i].Verifiable = zskValidity
+	}
+	keys = getDNSKEYs(m, KSK)
+	keyRes2 := make([]Key, len(keys))
+	for i, k := range keys {
+		in.checkKSKverifiability(ctx, &keyRes2[i], z.FQDN, k)
+		checkKey(k, &keyRes2[i], in.policies, in.now().Year())
+		if !keyRes2[i].Verifiable && z.FQDN != "." && k.Flags&dns.REVOKE == 0 {
+			key := k
+			keyRes2[i].RecommendedDS = dsRecords([]*dns.DNSKEY{&key})
+		}
+	}
+	z.Keys = append(keyRes1, keyRes2...)
+	z.KeyCount = len(z.Keys)
+	in.checkDS(ctx, z)
+	in.checkCDS(ctx, z)
+	in.checkRollover(ctx, z)
+}
+
+/* The function checks wether a ZSK (zone signing key) is verifiable by its
+corresponding KSK (key signing key). It also checks the time boundaries of the
+key signature.
+*/
+func (in *Inspector) checkZSKverifiability(ctx context.Context, fqdn string) bool {
+	m := in.dnssecQuery(ctx, fqdn, dns.TypeRRSIG, in.auth)
+	for _, r := range m.Answer {
+		if sig, ok := r.(*dns.RRSIG); ok && sig.TypeCovered == dns.TypeDNSKEY {
+			if !r.(*dns.RRSIG).ValidityPeriod(in.now()) {
+				return false
+			}
+			key := in.getKeyForRRSIG(ctx, fqdn, r)
+			if key == nil {
+				return false
+			}
+			records := in.getRRsCoveredByRRSIG(ctx, fqdn, r, "Answer")
+			if err := r.(*dns.RRSIG).Verify(key, records); err != nil {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+/* The function detects the authoritative nameservers for a zone.
+The zone content of each of their addresses is compared by checkZoneAt/checkConsistency.
+*/
+func (in *Inspector) checkAuthNS(ctx context.Context, fqdn string) []Nameserver {
+	m := in.dnssecQuery(ctx, fqdn, dns.TypeNS, in.resolver)
+	ret := []Nameserver{}
+	var x Nameserver
+	for _, r := range m.Answer {
+		if ns, ok := r.(*dns.NS); ok {
+			x = Nameserver{}
+			x.Name = ns.Ns
+			x.Addresses = in.resolveAddresses(ctx, x.Name)
+			ret = append(ret, x)
+		}
+	}
+	return ret
+}
+
+/* Checks if a given nameserver address resolves non-authoritative dns
+requests. A authoritative nameserver shouldn't resolve. The function checks...
+	* if recursion is available for a name outside of the zone
+	* if the name outside of the zone is answered without recursion (from cache)
+	* if the RA bit is set in authoritative answers (RA leak)
+The name outside of the zone is the first of the configured probe names that
+does not belong to the zone. The queries are not answered from the cache.
+*/
+func (in *Inspector) isResolver(ctx context.Context, a *Address, zone string) bool {
+	x := in.opts.ResolverProbes[0]
+	for _, p := range in.opts.ResolverProbes {
+		if zone == "." || !dns.IsSubDomain(dns.Fqdn(zone), dns.Fqdn(p)) {
+			x = p
+			break
+		}
+	}
+	a.Resolver, a.RecursionAvailable, a.CacheSnooping, a.RALeak = false, false, false, false
+	server := in.server(a.IP)
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(x), dns.TypeA)
+	if r, err := in.exchange(ctx, server, m); err == nil {
+		a.RecursionAvailable = answered(r, x)
+		a.Resolver = a.RecursionAvailable
+	}
+	m.RecursionDesired = false
+	if r, err := in.exchange(ctx, server, m); err == nil {
+		a.CacheSnooping = answered(r, x)
+		a.Resolver = a.Resolver || a.CacheSnooping
+	}
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	if r, err := in.exchange(ctx, server, m); err == nil {
+		a.RALeak = r.Authoritative && r.RecursionAvailable
+	}
+	return a.Resolver
+}
+
+/* Checks if r is a successful answer with RRs owned by name. The RA bit
+alone does not make a resolver, closed servers often set it in refusals.
+*/
+func answered(r *dns.Msg, name string) bool {
+	if r.Rcode != dns.RcodeSuccess {
+		return false
+	}
+	for _, rr := range r.Answer {
+		if equalNames(rr.Header().Name, name) {
+			return true
+		}
+	}
+	return false
+}
+
+/* Checks the validity of a KSK DNSKEY RR by checking the DS RR in the
+authoritative zone above. A KSK matched by a configured trust anchor needs no DS.
+*/
+func (in *Inspector) checkKSKverifiability(ctx context.Context, k *Key, fqdn string, key dns.DNSKEY) (bool, error) {
+	k.Verifiable = false
+	k.TrustAnchor = false
+	if isAnchored(&key, in.anchorsFor(fqdn)) {
+		k.Verifiable = true
+		k.TrustAnchor = true
+		return true, nil
+	}
+	ds, _, _, _ := in.getDSset(ctx, fqdn)
+	if len(ds) == 0 {
+		return false, errors.New("No DS RR for given key")
+	}
+	for _, rr := range ds {
+		if d, ok := rr.(*dns.DS); ok && dsMatchesKey(d, &key) {
+			k.Verifiable = true
+			return true, nil
+		}
+	}
+	return false, errors.New("DS does not match")
+}
+
+/* Takes a list of RRs as dns.Msg and returns a set of contained DNSKEY-RRs.
+ */
+func getDNSKEYs(m dns.Msg, t uint16) (ret []dns.DNSKEY) {
+	for _, r := range m.Answer {
+		if r.Header().Rrtype == dns.TypeDNSKEY && r.(*dns.DNSKEY).Flags&^dns.REVOKE == t {
+			ret = append(ret, *r.(*dns.DNSKEY))
+		}
+	}
+	return
+}
+
+/* Takes a list of RRs as dns.Msg and returns a set of contained RRs of a
+specified type.
+*/
+func getRRsigs(m dns.Msg, t uint16) (ret []*dns.RRSIG) {
+	x := m.Answer
+	if t == dns.TypeNS {
+		x = m.Ns
+	}
+	for _, r := range x {
+		if r.Header().Rrtype == dns.TypeRRSIG && r.(*dns.RRSIG).TypeCovered == t {
+			ret = append(ret, r.(*dns.RRSIG))
+		}
+	}
+	return
+}
+
+// Lists all names from fqdn up to the root,
+// e.g. {"www.bsi.de", "bsi.de", "de", "."} for www.bsi.de
+func pathNames(fqdn string) []string {
+	labels := dns.SplitDomainName(fqdn)
+	var ret []string
+	for i := range labels {
+		ret = append(ret, strings.Join(labels[i:], "."))
+	}
+	return append(ret, ".")
+}
+
+/* Detects the zone cuts along a list of names ordered from the target up to
+the root. Each name is classified by an SOA query: The apex of a zone answers
+with its own SOA RR, while a name inside a zone gets a referral to the SOA of
+the enclosing zone in the authority section. Each name is mapped to the zone
+it belongs to.
+*/
+func (in *Inspector) findZoneCuts(ctx context.Context, names []string) []PathName {
+	ret := make([]PathName, len(names))
+	zone := "."
+	for i := len(names) - 1; i >= 0; i-- {
+		ret[i].Name = names[i]
+		if in.isZoneApex(ctx, names[i]) {
+			ret[i].ZoneCut = true
+			zone = names[i]
+		}
+		ret[i].Zone = zone
+	}
+	return ret
+}
+
+// Checks if fqdn is the apex of a zone
+func (in *Inspector) isZoneApex(ctx context.Context, fqdn string) bool {
+	if fqdn == "." {
+		return true
+	}
+	m := in.dnssecQuery(ctx, fqdn, dns.TypeSOA, in.resolver)
+	for _, r := range m.Answer {
+		if soa, ok := r.(*dns.SOA); ok && equalNames(soa.Hdr.Name, fqdn) {
+			return true
+		}
+	}
+	if len(m.Answer) > 0 || len(m.Ns) > 0 {
+		return false
+	}
+	// No referral at all (e.g. SOA queries are refused), fall back to NS
+	m = in.dnssecQuery(ctx, fqdn, dns.TypeNS, in.resolver)
+	for _, r := range m.Answer {
+		if ns, ok := r.(*dns.NS); ok && equalNames(ns.Hdr.Name, fqdn) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,129 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Runs the zone checks against a single address of an authoritative
+// nameserver: the DNSKEY RRset, the SOA serial, the NSEC3PARAM RR and the
+// signatures over the DNSKEY and SOA RRsets as served by this address. soa is
+// the SOA response of the address received by checkAddress.
+func (in *Inspector) checkZoneAt(ctx context.Context, a *Address, zone string, soa *dns.Msg) {
+	server := in.server(a.IP)
+	apex := dns.Fqdn(zone)
+	var errs []string
+
+	m := in.dnssecQuery(ctx, apex, dns.TypeDNSKEY, server)
+	var keys []*dns.DNSKEY
+	a.KeyTags = nil
+	for _, r := range m.Answer {
+		if k, ok := r.(*dns.DNSKEY); ok && equalNames(k.Hdr.Name, apex) {
+			keys = append(keys, k)
+			a.KeyTags = append(a.KeyTags, k.KeyTag())
+		}
+	}
+	sort.Slice(a.KeyTags, func(i, j int) bool { return a.KeyTags[i] < a.KeyTags[j] })
+	if len(keys) > 0 {
+		if err := verifyRRset(keysToRRs(keys), getRRsigs(m, dns.TypeDNSKEY), keys, in.now()); err != nil {
+			errs = append(errs, "DNSKEY: "+err.Error())
+		}
+	}
+
+	a.SOASerial = 0
+	var rrs []dns.RR
+	for _, r := range soa.Answer {
+		if s, ok := r.(*dns.SOA); ok && equalNames(s.Hdr.Name, apex) {
+			a.SOASerial = s.Serial
+			rrs = append(rrs, s)
+		}
+	}
+	if len(keys) > 0 && len(rrs) > 0 {
+		if err := verifyRRset(rrs, getRRsigs(*soa, dns.TypeSOA), keys, in.now()); err != nil {
+			errs = append(errs, "SOA: "+err.Error())
+		}
+	}
+
+	m = in.dnssecQuery(ctx, apex, dns.TypeNSEC3PARAM, server)
+	a.NSEC3PARAM = ""
+	for _, r := range m.Answer {
+		if p, ok := r.(*dns.NSEC3PARAM); ok {
+			a.NSEC3PARAM = fmt.Sprintf("%d %d %d %s", p.Hash, p.Flags, p.Iterations, p.Salt)
+		}
+	}
+
+	a.CDS = sortedRdata(in.dnssecQuery(ctx, apex, dns.TypeCDS, server), apex, dns.TypeCDS)
+	a.CDNSKEY = sortedRdata(in.dnssecQuery(ctx, apex, dns.TypeCDNSKEY, server), apex, dns.TypeCDNSKEY)
+
+	a.Validation = len(keys) > 0 && len(errs) == 0
+	a.ValidationError = strings.Join(errs, "; ")
+}
+
+// Compares the results of the reachable addresses of all authoritative
+// nameservers of a zone and lists every check they disagree on.
+func checkConsistency(z *Zone) {
+	z.Inconsistencies = nil
+	serials := map[string][]string{}
+	keyTags := map[string][]string{}
+	nsec3 := map[string][]string{}
+	validation := map[string][]string{}
+	cds := map[string][]string{}
+	cdnskey := map[string][]string{}
+	for _, n := range z.AutoritativeNS {
+		for _, a := range n.Addresses {
+			if !a.Reachable {
+				continue
+			}
+			name := n.Name + " " + a.IP
+			serials[fmt.Sprint(a.SOASerial)] = append(serials[fmt.Sprint(a.SOASerial)], name)
+			keyTags[fmt.Sprint(a.KeyTags)] = append(keyTags[fmt.Sprint(a.KeyTags)], name)
+			nsec3[a.NSEC3PARAM] = append(nsec3[a.NSEC3PARAM], name)
+			cds[strings.Join(a.CDS, ", ")] = append(cds[strings.Join(a.CDS, ", ")], name)
+			cdnskey[strings.Join(a.CDNSKEY, ", ")] = append(cdnskey[strings.Join(a.CDNSKEY, ", ")], name)
+			v := "valid"
+			if !a.Validation {
+				v = "not valid"
+				if a.ValidationError != "" {
+					v += " (" + a.ValidationError + ")"
+				}
+			}
+			validation[v] = append(validation[v], name)
+		}
+	}
+	z.addInconsistency("SOA serial", serials)
+	z.addInconsistency("DNSKEY key tags", keyTags)
+	z.addInconsistency("NSEC3PARAM", nsec3)
+	z.addInconsistency("signatures", validation)
+	z.addInconsistency("CDS", cds)
+	z.addInconsistency("CDNSKEY", cdnskey)
+	z.Inconsistent = len(z.Inconsistencies) > 0
+}
+
+// Returns the RDATA of the RRs of type t owned by name, sorted
+func sortedRdata(m dns.Msg, name string, t uint16) (ret []string) {
+	for _, rr := range rrsetOf(m, name, t) {
+		ret = append(ret, rdataString(rr))
+	}
+	sort.Strings(ret)
+	return
+}
+
+// Records an inconsistency if the addresses report more than one value
+func (z *Zone) addInconsistency(check string, values map[string][]string) {
+	if len(values) < 2 {
+		return
+	}
+	var parts []string
+	for v, servers := range values {
+		if v == "" {
+			v = "none"
+		}
+		parts = append(parts, fmt.Sprintf("%s: %s", strings.Join(servers, ", "), v))
+	}
+	sort.Strings(parts)
+	z.Inconsistencies = append(z.Inconsistencies, fmt.Sprintf("inconsistent %s - %s", check, strings.Join(parts, "; ")))
+}
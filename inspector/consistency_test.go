@@ -0,0 +1,80 @@
+package inspector
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestCheckConsistency(t *testing.T) {
+	z := &Zone{AutoritativeNS: []Nameserver{
+		{Name: "ns1.example.", Addresses: []Address{
+			{IP: "192.0.2.1", Reachable: true, SOASerial: 2024010101, KeyTags: []uint16{1, 2}, Validation: true}}},
+		{Name: "ns2.example.", Addresses: []Address{
+			{IP: "192.0.2.2", Reachable: true, SOASerial: 2024010101, KeyTags: []uint16{1, 2}, Validation: true},
+			{IP: "2001:db8::2", IPv6: true}}},
+	}}
+	checkConsistency(z)
+	if z.Inconsistent || len(z.Inconsistencies) != 0 {
+		t.Errorf("Consistent nameservers reported as inconsistent: %v", z.Inconsistencies)
+	}
+
+	// Stale address of a secondary serving an old serial with expired signatures
+	z.AutoritativeNS[1].Addresses[1] = Address{IP: "2001:db8::2", IPv6: true, Reachable: true, SOASerial: 2023120101,
+		KeyTags: []uint16{1, 2}, ValidationError: "SOA: The validity period expired"}
+	checkConsistency(z)
+	if !z.Inconsistent || len(z.Inconsistencies) != 2 {
+		t.Fatalf("Unexpected inconsistencies: %v", z.Inconsistencies)
+	}
+	if !strings.HasPrefix(z.Inconsistencies[0], "inconsistent SOA serial") ||
+		!strings.Contains(z.Inconsistencies[0], "ns2.example. 2001:db8::2: 2023120101") {
+		t.Errorf("Unexpected serial inconsistency: %s", z.Inconsistencies[0])
+	}
+	if !strings.HasPrefix(z.Inconsistencies[1], "inconsistent signatures") {
+		t.Errorf("Unexpected signature inconsistency: %s", z.Inconsistencies[1])
+	}
+}
+
+func TestCheckAddress(t *testing.T) {
+	zone := newTestZone(t, "example.")
+	soa := mustRRs(t, "example. 3600 IN SOA ns1.example. hostmaster.example. 2024010101 7200 3600 1209600 3600")[0]
+	keys := zone.keyset(t)
+	signed, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		switch r.Question[0].Qtype {
+		case dns.TypeSOA:
+			m.Answer = []dns.RR{soa, zone.sign(t, soa)}
+		case dns.TypeDNSKEY:
+			m.Answer = keys
+		}
+		if opt := r.IsEdns0(); opt != nil {
+			m.SetEdns0(opt.UDPSize(), opt.Do())
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+	// Address that lost the zone and answers without an SOA RR
+	lame, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+
+	in := New(Options{Resolver: newFakeResolver(t)})
+	a := &Address{IP: signed}
+	in.checkAddress(context.Background(), a, "example")
+	if !a.Reachable || !a.EDNS0 || !a.DNSSEC || a.SOASerial != 2024010101 || !a.Validation ||
+		len(a.KeyTags) != 1 || a.KeyTags[0] != zone.key.KeyTag() {
+		t.Errorf("Unexpected result %+v", a)
+	}
+	a.IP = lame
+	in.checkAddress(context.Background(), a, "example")
+	if !a.Reachable || a.SOASerial != 0 || a.KeyTags != nil || a.Validation {
+		t.Errorf("Results of the previous address kept: %+v", a)
+	}
+}
@@ -0,0 +1,382 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Type queried at the zone apex to provoke a NODATA response
+const nodataType = dns.TypeNULL
+
+// Checks the authenticated denial of existence of a zone. A random name below
+// the apex (see Zone.nxdomainProbe) is queried to get an NXDOMAIN response and the apex is queried for a
+// type that does not exist to get a NODATA response. The NSEC or NSEC3 RRs of
+// both responses have to be signed by the zone and prove the non-existence
+// (RFC 4035 section 5.4, RFC 5155 section 8). Unsigned zones are skipped.
+func (in *Inspector) checkDenialOfExistence(ctx context.Context, z *Zone) {
+	keys, _ := in.getDNSKEYset(ctx, z.FQDN)
+	if len(keys) == 0 {
+		return
+	}
+	d := &Denial{}
+	apex := dns.Fqdn(z.FQDN)
+	qname := z.nxdomainProbe()
+
+	m := in.dnssecQuery(ctx, qname, dns.TypeA, in.auth)
+	err := evaluateNXDOMAIN(d, m, qname, keys, in.now())
+	d.NXDOMAIN = err == nil
+	if err != nil {
+		d.NXDOMAINError = err.Error()
+	}
+
+	m = in.dnssecQuery(ctx, apex, nodataType, in.auth)
+	err = denialResponseError(m, dns.RcodeSuccess)
+	if err == nil {
+		err = verifyDenialRRs(d, m.Ns, keys, in.now())
+	}
+	if err == nil {
+		err = proveNODATA(apex, nodataType, m.Ns)
+	}
+	d.NODATA = err == nil
+	if err != nil {
+		d.NODATAError = err.Error()
+	}
+	z.Denial = d
+}
+
+// Checks the response to a query for the non-existent name qname. Besides
+// NXDOMAIN the compact denial of RFC 9824 is accepted: a NOERROR response
+// with a signed NSEC RR owned by qname that lists no types but NSEC, RRSIG
+// and NXNAME.
+func evaluateNXDOMAIN(d *Denial, m dns.Msg, qname string, keys []*dns.DNSKEY, now time.Time) error {
+	rcode := dns.RcodeNameError
+	d.Compact = m.Rcode == dns.RcodeSuccess && compactDenial(qname, m.Ns)
+	if d.Compact {
+		rcode = dns.RcodeSuccess
+	}
+	err := denialResponseError(m, rcode)
+	if err == nil {
+		err = verifyDenialRRs(d, m.Ns, keys, now)
+	}
+	if err == nil && !d.Compact {
+		err = proveNXDOMAIN(qname, m.Ns)
+	}
+	return err
+}
+
+// Checks if an NSEC RR owned by qname denies its existence in the compact
+// form of RFC 9824 section 3
+func compactDenial(qname string, ns []dns.RR) bool {
+	nsec, _ := splitDenialRRs(ns)
+	for _, n := range nsec {
+		if !equalNames(n.Hdr.Name, qname) {
+			continue
+		}
+		for _, t := range n.TypeBitMap {
+			if t != dns.TypeNSEC && t != dns.TypeRRSIG && t != dns.TypeNXNAME {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// Returns the random name the NXDOMAIN responses of the zone are tested
+// with. The denial, enumeration, NSEC3 and response size checks share it, so
+// the zone is probed with a single name.
+func (z *Zone) nxdomainProbe() string {
+	if z.probe == "" {
+		z.probe = randomName(z.FQDN)
+	}
+	return z.probe
+}
+
+// Returns a random name below apex that is unlikely to exist
+func randomName(apex string) string {
+	if apex == "." {
+		return fmt.Sprintf("dnssec-inspector-%08x.", rand.Uint32())
+	}
+	return fmt.Sprintf("dnssec-inspector-%08x.%s", rand.Uint32(), dns.Fqdn(apex))
+}
+
+// Checks that a response is a negative answer with the expected rcode
+func denialResponseError(m dns.Msg, rcode int) error {
+	if m.Rcode != rcode {
+		return fmt.Errorf("expected %s, got %s", dns.RcodeToString[rcode], dns.RcodeToString[m.Rcode])
+	}
+	if len(m.Answer) > 0 {
+		return errors.New("the response contains an answer (wildcard?)")
+	}
+	return nil
+}
+
+// Verifies the signatures of all NSEC and NSEC3 RRs of an authority section
+// at now and records the denial method used.
+func verifyDenialRRs(d *Denial, ns []dns.RR, keys []*dns.DNSKEY, now time.Time) error {
+	found := false
+	for _, rr := range ns {
+		t := rr.Header().Rrtype
+		if t != dns.TypeNSEC && t != dns.TypeNSEC3 {
+			continue
+		}
+		found = true
+		d.Method = dns.TypeToString[t]
+		var sigs []*dns.RRSIG
+		for _, s := range ns {
+			if sig, ok := s.(*dns.RRSIG); ok && sig.TypeCovered == t && equalNames(sig.Hdr.Name, rr.Header().Name) {
+				sigs = append(sigs, sig)
+			}
+		}
+		if err := verifyRRset([]dns.RR{rr}, sigs, keys, now); err != nil {
+			return fmt.Errorf("%s %s: %s", d.Method, rr.Header().Name, err)
+		}
+	}
+	if !found {
+		return errors.New("no NSEC or NSEC3 RRs in the response")
+	}
+	return nil
+}
+
+// Checks if the NSEC or NSEC3 RRs prove that qname does not exist
+func proveNXDOMAIN(qname string, ns []dns.RR) error {
+	nsec, nsec3 := splitDenialRRs(ns)
+	if len(nsec3) > 0 {
+		return nsec3ProveNXDOMAIN(qname, nsec3)
+	}
+	return nsecProveNXDOMAIN(qname, nsec)
+}
+
+// Checks if the NSEC or NSEC3 RRs prove that qname has no RRs of type qtype
+func proveNODATA(qname string, qtype uint16, ns []dns.RR) error {
+	nsec, nsec3 := splitDenialRRs(ns)
+	for _, n := range nsec {
+		if equalNames(n.Hdr.Name, qname) {
+			return checkTypeBitmap(n.TypeBitMap, qtype)
+		}
+	}
+	for _, n := range nsec3 {
+		if n.Match(qname) {
+			return checkTypeBitmap(n.TypeBitMap, qtype)
+		}
+	}
+	return fmt.Errorf("no NSEC/NSEC3 RR matches %s", qname)
+}
+
+// RFC 4035 section 5.4: An NSEC RR has to cover qname and another one has to
+// cover the wildcard at the closest encloser.
+func nsecProveNXDOMAIN(qname string, nsec []*dns.NSEC) error {
+	var cover *dns.NSEC
+	for _, n := range nsec {
+		if nsecCovers(n, qname) {
+			cover = n
+			break
+		}
+	}
+	if cover == nil {
+		return fmt.Errorf("no NSEC RR covers %s", qname)
+	}
+	ce := commonAncestor(qname, cover.Hdr.Name)
+	if a := commonAncestor(qname, cover.NextDomain); dns.CountLabel(a) > dns.CountLabel(ce) {
+		ce = a
+	}
+	wildcard := "*." + ce
+	if ce == "." {
+		wildcard = "*."
+	}
+	for _, n := range nsec {
+		if nsecCovers(n, wildcard) {
+			return nil
+		}
+	}
+	return fmt.Errorf("no NSEC RR covers the wildcard %s", wildcard)
+}
+
+// RFC 5155 section 8.4: The closest encloser has to be matched, the next
+// closer name and the wildcard at the closest encloser have to be covered.
+func nsec3ProveNXDOMAIN(qname string, nsec3 []*dns.NSEC3) error {
+	ce, nextCloser := nsec3ClosestEncloser(qname, nsec3)
+	if ce == "" {
+		return fmt.Errorf("no NSEC3 RR matches a closest encloser of %s", qname)
+	}
+	if nsec3Cover(nsec3, nextCloser) == nil {
+		return fmt.Errorf("no NSEC3 RR covers the next closer name %s", nextCloser)
+	}
+	wildcard := "*." + ce
+	if ce == "." {
+		wildcard = "*."
+	}
+	if nsec3Cover(nsec3, wildcard) == nil {
+		return fmt.Errorf("no NSEC3 RR covers the wildcard %s", wildcard)
+	}
+	return nil
+}
+
+// Checks if the NSEC or NSEC3 RRs of a DS response prove that the delegation
+// to zone has no DS RR. Either an NSEC/NSEC3 RR for the delegation point has
+// the NS but not the DS and SOA bit set (RFC 4035 section 5.2), or the
+// delegation is covered by an opt-out NSEC3 RR (RFC 5155 section 8.6).
+func proveNoDS(zone string, ns []dns.RR) error {
+	nsec, nsec3 := splitDenialRRs(ns)
+	for _, n := range nsec {
+		if equalNames(n.Hdr.Name, zone) {
+			return checkNoDSBitmap(n.TypeBitMap)
+		}
+	}
+	for _, n := range nsec3 {
+		if n.Match(zone) {
+			return checkNoDSBitmap(n.TypeBitMap)
+		}
+	}
+	if len(nsec3) > 0 {
+		ce, nextCloser := nsec3ClosestEncloser(zone, nsec3)
+		if ce == "" {
+			return fmt.Errorf("no NSEC3 RR matches a closest encloser of %s", zone)
+		}
+		n := nsec3Cover(nsec3, nextCloser)
+		if n == nil {
+			return fmt.Errorf("no NSEC3 RR covers the next closer name %s", nextCloser)
+		}
+		if n.Flags&1 == 0 {
+			return fmt.Errorf("NSEC3 RR covering %s has no opt-out flag", nextCloser)
+		}
+		return nil
+	}
+	return fmt.Errorf("no NSEC/NSEC3 RR proves the absence of a DS RR for %s", zone)
+}
+
+// The type bitmap of a delegation without DS RR has the NS bit set, but
+// neither the DS bit nor the SOA bit of the child zone's apex
+func checkNoDSBitmap(bitmap []uint16) error {
+	hasNS := false
+	for _, t := range bitmap {
+		switch t {
+		case dns.TypeDS, dns.TypeSOA:
+			return fmt.Errorf("type bitmap contains %s", dns.TypeToString[t])
+		case dns.TypeNS:
+			hasNS = true
+		}
+	}
+	if !hasNS {
+		return errors.New("type bitmap does not contain NS")
+	}
+	return nil
+}
+
+// Searches the closest encloser of qname that is matched by an NSEC3 RR and
+// returns it with the next closer name (RFC 5155 section 7.2.1). ce is empty
+// if there is none.
+func nsec3ClosestEncloser(qname string, nsec3 []*dns.NSEC3) (ce, nextCloser string) {
+	labels := dns.SplitDomainName(qname)
+	for i := 1; i <= len(labels); i++ {
+		c := dns.Fqdn(strings.Join(labels[i:], "."))
+		if nsec3Matches(nsec3, c) {
+			return c, dns.Fqdn(strings.Join(labels[i-1:], "."))
+		}
+	}
+	return "", ""
+}
+
+func nsec3Matches(nsec3 []*dns.NSEC3, name string) bool {
+	for _, n := range nsec3 {
+		if n.Match(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the NSEC3 RR covering name or nil
+func nsec3Cover(nsec3 []*dns.NSEC3, name string) *dns.NSEC3 {
+	for _, n := range nsec3 {
+		if n.Cover(name) {
+			return n
+		}
+	}
+	return nil
+}
+
+// Checks if name lies strictly between owner and next name of the NSEC RR in
+// canonical order. The last NSEC RR of a zone points back to the apex.
+func nsecCovers(n *dns.NSEC, name string) bool {
+	owner := canonicalCompare(n.Hdr.Name, name)
+	next := canonicalCompare(name, n.NextDomain)
+	if canonicalCompare(n.Hdr.Name, n.NextDomain) < 0 {
+		return owner < 0 && next < 0
+	}
+	return owner < 0 || next < 0
+}
+
+// Checks that neither qtype nor CNAME is set in an NSEC/NSEC3 type bitmap
+func checkTypeBitmap(bitmap []uint16, qtype uint16) error {
+	for _, t := range bitmap {
+		if t == qtype || t == dns.TypeCNAME {
+			return fmt.Errorf("type bitmap contains %s", dns.TypeToString[t])
+		}
+	}
+	return nil
+}
+
+func splitDenialRRs(ns []dns.RR) (nsec []*dns.NSEC, nsec3 []*dns.NSEC3) {
+	for _, rr := range ns {
+		switch t := rr.(type) {
+		case *dns.NSEC:
+			nsec = append(nsec, t)
+		case *dns.NSEC3:
+			nsec3 = append(nsec3, t)
+		}
+	}
+	return
+}
+
+// Compares two domain names in canonical DNS name order (RFC 4034 section 6.1)
+func canonicalCompare(a, b string) int {
+	la := dns.SplitDomainName(strings.ToLower(a))
+	lb := dns.SplitDomainName(strings.ToLower(b))
+	for i, j := len(la)-1, len(lb)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(unescapeLabel(la[i]), unescapeLabel(lb[j])); c != 0 {
+			return c
+		}
+	}
+	return len(la) - len(lb)
+}
+
+// Replaces the \X and \DDD escapes of a label in presentation format by
+// the octets they stand for
+func unescapeLabel(l string) string {
+	if !strings.Contains(l, "\\") {
+		return l
+	}
+	var b strings.Builder
+	for i := 0; i < len(l); i++ {
+		if l[i] != '\\' || i+1 >= len(l) {
+			b.WriteByte(l[i])
+			continue
+		}
+		if i+3 < len(l) && isDigit(l[i+1]) && isDigit(l[i+2]) && isDigit(l[i+3]) {
+			b.WriteByte((l[i+1]-'0')*100 + (l[i+2]-'0')*10 + (l[i+3] - '0'))
+			i += 3
+		} else {
+			b.WriteByte(l[i+1])
+			i++
+		}
+	}
+	return b.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Returns the longest common ancestor of two domain names
+func commonAncestor(a, b string) string {
+	n := dns.CompareDomainName(a, b)
+	labels := dns.SplitDomainName(a)
+	return dns.Fqdn(strings.Join(labels[len(labels)-n:], "."))
+}
@@ -0,0 +1,165 @@
+package inspector
+
+import (
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func mustRRs(t *testing.T, records ...string) []dns.RR {
+	var ret []dns.RR
+	for _, s := range records {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("Cannot parse %q: %s", s, err)
+		}
+		ret = append(ret, rr)
+	}
+	return ret
+}
+
+func TestCanonicalCompare(t *testing.T) {
+	// Example from RFC 4034 section 6.1
+	names := []string{"example.", "a.example.", "yljkjljk.a.example.", "Z.a.example.",
+		"zABC.a.EXAMPLE.", "z.example.", "\\001.z.example.", "*.z.example.", "\\200.z.example."}
+	for i := 0; i < len(names)-1; i++ {
+		if canonicalCompare(names[i], names[i+1]) >= 0 {
+			t.Errorf("%s is not sorted before %s", names[i], names[i+1])
+		}
+	}
+}
+
+func TestNSECDenial(t *testing.T) {
+	ns := mustRRs(t,
+		"example. 3600 IN NSEC a.example. NS SOA RRSIG NSEC DNSKEY",
+		"a.example. 3600 IN NSEC c.example. A RRSIG NSEC",
+		"c.example. 3600 IN NSEC example. A RRSIG NSEC",
+	)
+	if err := proveNXDOMAIN("b.example.", ns); err != nil {
+		t.Errorf("NXDOMAIN proof for b.example. fails: %s", err)
+	}
+	if err := proveNXDOMAIN("b.example.", ns[1:2]); err == nil {
+		t.Error("NXDOMAIN proof without wildcard proof is accepted")
+	}
+	if err := proveNXDOMAIN("a.example.", ns); err == nil {
+		t.Error("NXDOMAIN proof for existing name is accepted")
+	}
+	if err := proveNODATA("example.", nodataType, ns); err != nil {
+		t.Errorf("NODATA proof fails: %s", err)
+	}
+	if err := proveNODATA("example.", dns.TypeSOA, ns); err == nil {
+		t.Error("NODATA proof for existing type is accepted")
+	}
+}
+
+func TestNSEC3Denial(t *testing.T) {
+	var hashes []string
+	for _, n := range []string{"example.", "a.example."} {
+		hashes = append(hashes, dns.HashName(n, dns.SHA1, 0, ""))
+	}
+	sort.Strings(hashes)
+	var ns []dns.RR
+	for i, h := range hashes {
+		ns = append(ns, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h) + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET, Ttl: 3600},
+			Hash:       dns.SHA1,
+			NextDomain: hashes[(i+1)%len(hashes)],
+			TypeBitMap: []uint16{dns.TypeA, dns.TypeRRSIG},
+		})
+	}
+	if err := proveNXDOMAIN("x.example.", ns); err != nil {
+		t.Errorf("NXDOMAIN proof for x.example. fails: %s", err)
+	}
+	if err := proveNODATA("a.example.", nodataType, ns); err != nil {
+		t.Errorf("NODATA proof fails: %s", err)
+	}
+	if err := proveNODATA("a.example.", dns.TypeA, ns); err == nil {
+		t.Error("NODATA proof for existing type is accepted")
+	}
+	var withoutApex []dns.RR
+	for _, rr := range ns {
+		if !rr.(*dns.NSEC3).Match("example.") {
+			withoutApex = append(withoutApex, rr)
+		}
+	}
+	if err := proveNXDOMAIN("x.example.", withoutApex); err == nil {
+		t.Error("NXDOMAIN proof without closest encloser is accepted")
+	}
+}
+
+func TestVerifyDenialRRs(t *testing.T) {
+	z := newTestZone(t, "example.")
+	nsec := mustRRs(t, "example. 3600 IN NSEC a.example. NS SOA RRSIG NSEC DNSKEY")[0]
+	d := &Denial{}
+	if err := verifyDenialRRs(d, []dns.RR{nsec, z.sign(t, nsec)}, []*dns.DNSKEY{z.key}, time.Now()); err != nil {
+		t.Errorf("Signed NSEC RR does not validate: %s", err)
+	}
+	if d.Method != "NSEC" {
+		t.Errorf("Method = %q, want NSEC", d.Method)
+	}
+	if err := verifyDenialRRs(d, []dns.RR{nsec}, []*dns.DNSKEY{z.key}, time.Now()); err == nil {
+		t.Error("Unsigned NSEC RR validates")
+	}
+}
+
+func TestCompactDenial(t *testing.T) {
+	z := newTestZone(t, "example.")
+	qname := "x.example."
+	nsec := mustRRs(t, "x.example. 3600 IN NSEC \\000.x.example. RRSIG NSEC NXNAME")[0]
+	m := dns.Msg{Ns: []dns.RR{nsec, z.sign(t, nsec)}}
+	d := &Denial{}
+	if err := evaluateNXDOMAIN(d, m, qname, []*dns.DNSKEY{z.key}, time.Now()); err != nil || !d.Compact {
+		t.Errorf("Compact denial rejected: %v (%+v)", err, d)
+	}
+	// An NSEC RR listing other types proves that the name exists
+	nsec = mustRRs(t, "x.example. 3600 IN NSEC \\000.x.example. A RRSIG NSEC")[0]
+	m = dns.Msg{Ns: []dns.RR{nsec, z.sign(t, nsec)}}
+	d = &Denial{}
+	if err := evaluateNXDOMAIN(d, m, qname, []*dns.DNSKEY{z.key}, time.Now()); err == nil || d.Compact {
+		t.Errorf("NOERROR response for an existing name accepted (%+v)", d)
+	}
+}
+
+func TestProveNoDS(t *testing.T) {
+	tests := []struct {
+		nsec  string
+		valid bool
+	}{
+		{"child.example. 3600 IN NSEC d.example. NS RRSIG NSEC", true},
+		{"child.example. 3600 IN NSEC d.example. NS DS RRSIG NSEC", false},
+		{"child.example. 3600 IN NSEC d.example. NS SOA RRSIG NSEC DNSKEY", false},
+		{"a.example. 3600 IN NSEC d.example. A RRSIG NSEC", false},
+	}
+	for _, test := range tests {
+		err := proveNoDS("child.example.", mustRRs(t, test.nsec))
+		if (err == nil) != test.valid {
+			t.Errorf("proveNoDS with %s: %v", test.nsec, err)
+		}
+	}
+
+	// Opt-out NSEC3 RR covering the delegation
+	apex := dns.HashName("example.", dns.SHA1, 0, "")
+	other := dns.HashName("other.example.", dns.SHA1, 0, "")
+	var ns []dns.RR
+	for _, h := range [][2]string{{apex, other}, {other, apex}} {
+		ns = append(ns, &dns.NSEC3{
+			Hdr:        dns.RR_Header{Name: strings.ToLower(h[0]) + ".example.", Rrtype: dns.TypeNSEC3, Class: dns.ClassINET},
+			Hash:       dns.SHA1,
+			Flags:      1,
+			NextDomain: h[1],
+			TypeBitMap: []uint16{dns.TypeNS, dns.TypeSOA},
+		})
+	}
+	if err := proveNoDS("child.example.", ns); err != nil {
+		t.Errorf("Opt-out proof is rejected: %s", err)
+	}
+	for _, rr := range ns {
+		rr.(*dns.NSEC3).Flags = 0
+	}
+	if err := proveNoDS("child.example.", ns); err == nil {
+		t.Error("Proof without opt-out is accepted")
+	}
+}
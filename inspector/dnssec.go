@@ -0,0 +1,220 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/miekg/dns"
+)
+
+type validationError struct {
+	rr  dns.RR
+	msg string
+}
+
+func (e *validationError) Error() string {
+	return fmt.Sprintf("%s - %s", e.rr.Header().String(), e.msg)
+}
+
+/* Queries for a given fully qualified domain name and a given type of resource
+records. It also includes the DNSSEC relevant matrial.
+*/
+func (in *Inspector) dnssecQuery(ctx context.Context, fqdn string, rrType uint16, resolver Resolver) dns.Msg {
+	cacheID := ""
+	if in.opts.Cache != "" {
+		cacheID = in.cacheFile(fqdn, rrType, resolver)
+	}
+	if cacheID != "" {
+		info, err := os.Stat(cacheID)
+		if err == nil {
+			if in.cacheValid(info) {
+				data, _ := ioutil.ReadFile(cacheID)
+				rc := new(dns.Msg)
+				rc.Unpack(data)
+				Info.Printf("Cache hit fpr %s\n", fqdn)
+				return *rc
+			}
+			//Remove old chache file
+			err = os.Remove(cacheID)
+			if err != nil {
+				Warning.Printf("Failed to remove file: %s\n", cacheID)
+			} else {
+				Info.Printf("Removed cache file: %s\n", cacheID)
+			}
+		}
+	}
+
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(fqdn), rrType)
+	m.SetEdns0(4096, true)
+	m.RecursionDesired = true
+	// A validating resolver answers SERVFAIL for bogus data unless checking
+	// is disabled, the inspector validates itself
+	m.CheckingDisabled = true
+	if in.opts.Replay {
+		// Nothing is sent while replaying, recorded failures stay failures
+		if _, err := os.Stat(cacheID + failureSuffix); cacheID == "" || err != nil {
+			replaying(ctx).add(queryString(fqdn, rrType, resolver))
+		}
+		r := new(dns.Msg)
+		r.SetRcode(m, dns.RcodeServerFailure)
+		return *r
+	}
+	r, err := resolver.Exchange(ctx, m)
+	if err != nil {
+		Warning.Printf("Query for %s failed: %s\n", fqdn, err)
+	}
+	if r != nil && r.Truncated {
+		Warning.Printf("Response for %s is truncated\n", fqdn)
+	}
+	if r == nil {
+		// No server answered, failures are only reused when replaying
+		Warning.Printf("Cant resolve dns question with server(s) %s\n", resolverID(resolver))
+		if cacheID != "" {
+			in.record(cacheID, nil, err)
+		}
+		r = new(dns.Msg)
+		r.SetRcode(m, dns.RcodeServerFailure)
+		return *r
+	}
+	if cacheID != "" {
+		in.record(cacheID, r, nil)
+	}
+	return *r
+}
+
+// Checks the existance of RRSIG rescource records for a given domain
+func (in *Inspector) checkExistence(ctx context.Context, res *Result, fqdn string) bool {
+	r := in.dnssecQuery(ctx, fqdn, dns.TypeRRSIG, in.resolver)
+	if r.Answer == nil {
+		res.DNSSEC = false
+		Info.Printf("Couldnt verify DNSSEC Existance for %s\n", fqdn)
+		return false
+	}
+	res.DNSSEC = true
+	return true
+}
+
+/*
+Checks the existance of NSEC3 records.
+RFC5155#Section-3
+If an NSEC3PARAM RR is present at the apex of a zone with a Flags field
+value of zero, then thre MUST be an NSEC3 RR using the same hash algorithm,
+iterations, and salt parameters … */
+func (in *Inspector) checkNSEC3Existence(ctx context.Context, z *Zone, fqdn string) bool {
+	r := in.dnssecQuery(ctx, fqdn, dns.TypeNSEC3PARAM, in.resolver)
+	if len(r.Answer) > 0 {
+		for _, i := range r.Answer {
+			if p, ok := i.(*dns.NSEC3PARAM); ok && p.Flags == 0 {
+				z.NSEC3 = true
+				z.NSEC3iter = int(p.Iterations)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+/* Checks if the RRSIG records for fqdn can be validated */
+func (in *Inspector) checkRRValidation(ctx context.Context, fqdn string, out *Zone) bool {
+	// get RRSIG RR to check
+	r := in.dnssecQuery(ctx, fqdn, dns.TypeANY, in.auth)
+	var err error
+	if out.ValidatesAnswer, err = in.checkSection(ctx, fqdn, r.Answer, "Answer"); err != nil {
+		out.ValidationErrorAnswer = err.Error()
+	}
+	if out.ValidatesNs, err = in.checkSection(ctx, fqdn, r.Ns, "Ns"); err != nil {
+		out.ValidationErrorNs = err.Error()
+	}
+	if out.ValidatesExtra, err = in.checkSection(ctx, fqdn, r.Extra, "Extra"); err != nil {
+		out.ValidationErrorExtra = err.Error()
+	} else {
+		out.ValidationErrorExtra = ""
+	}
+	if out.ValidatesAnswer && out.ValidatesNs && out.ValidatesExtra {
+		out.Validation = true
+	} else {
+		out.Validation = false
+	}
+	return out.Validation
+}
+
+// Checks a given list of RRs (r) from a section on RRSIG RRs and validates them
+func (in *Inspector) checkSection(ctx context.Context, fqdn string, r []dns.RR, section string) (bool, error) {
+	ret := true
+	for _, rr := range r {
+		records := []dns.RR{}
+		if rr.Header().Rrtype == dns.TypeRRSIG && rr.(*dns.RRSIG).TypeCovered != dns.TypeDNSKEY { // Filter on RRSIG records
+			if !rr.(*dns.RRSIG).ValidityPeriod(in.now()) {
+				return false, &validationError{rr, "The validity period expired"}
+			}
+			d := rr.Header().Name
+			key := in.getKeyForRRSIG(ctx, d, rr)
+			if key == nil {
+				errstr := fmt.Sprintf("No DNSKEY with key tag %d found for %s", rr.(*dns.RRSIG).KeyTag, rr.(*dns.RRSIG).SignerName)
+				return false, &validationError{rr, errstr}
+			}
+			if section == "Extra" {
+				for _, i := range r {
+					if i.Header().Rrtype == rr.(*dns.RRSIG).TypeCovered && i.Header().Name == rr.Header().Name {
+						records = append(records, i)
+					}
+				}
+			} else {
+				records = in.getRRsCoveredByRRSIG(ctx, fqdn, rr, section)
+				if section == "Ns" && len(records) == 0 {
+					return true, nil
+				}
+			}
+			err := rr.(*dns.RRSIG).Verify(key, records)
+			if err != nil {
+				errstr := fmt.Sprintf("Cannot validate the siganture cryptographically: %s", err)
+				return false, &validationError{rr, errstr}
+			}
+		}
+	}
+	return ret, nil
+}
+
+// Loads and returns the DNSKEY that made the signature in RRSIG RR, nil if
+// the signer publishes no such key
+func (in *Inspector) getKeyForRRSIG(ctx context.Context, fqdn string, r dns.RR) *dns.DNSKEY {
+	m := in.dnssecQuery(ctx, r.(*dns.RRSIG).SignerName, dns.TypeDNSKEY, in.resolver)
+	for _, i := range m.Answer {
+		if k, ok := i.(*dns.DNSKEY); ok {
+			if k.KeyTag() == r.(*dns.RRSIG).KeyTag {
+				return k
+			}
+		}
+	}
+	return nil
+}
+
+func (in *Inspector) getRRsCoveredByRRSIG(ctx context.Context, fqdn string, rr dns.RR, section string) []dns.RR {
+	m := in.dnssecQuery(ctx, fqdn, rr.(*dns.RRSIG).TypeCovered, in.auth)
+	var ret []dns.RR
+	for _, r := range m.Answer {
+		if _, ok := r.(*dns.RRSIG); !ok {
+			if r.Header().Rrtype == rr.(*dns.RRSIG).TypeCovered && r.Header().Name == rr.Header().Name {
+				ret = append(ret, r)
+			}
+		}
+	}
+	for _, r := range m.Ns {
+		if _, ok := r.(*dns.RRSIG); !ok {
+			if r.Header().Rrtype == rr.(*dns.RRSIG).TypeCovered && r.Header().Name == rr.Header().Name {
+				ret = append(ret, r)
+			}
+		}
+	}
+	for _, r := range m.Extra {
+		if _, ok := r.(*dns.RRSIG); !ok {
+			if r.Header().Rrtype == rr.(*dns.RRSIG).TypeCovered && r.Header().Name == rr.Header().Name {
+				ret = append(ret, r)
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,197 @@
+package inspector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestBundDE(t *testing.T) {
+	res, _ := New(Options{}).Inspect(context.Background(), "bund.de")
+	d, _ := json.Marshal(res)
+	ioutil.WriteFile("outest.json", d, 0644)
+}
+
+func TestOutputANYwithDNSSECrrs(t *testing.T) {
+	fqdn := "bsi.de"
+	in := New(Options{})
+	m := in.dnssecQuery(context.Background(), fqdn, dns.TypeA, in.resolver)
+	fmt.Printf("\nAnswer Section: \n")
+	for _, x := range m.Answer {
+		fmt.Printf("%v\n", x)
+	}
+	fmt.Printf("\nNs Section: \n")
+	for _, x := range m.Ns {
+		fmt.Printf("%v\n", x)
+	}
+	fmt.Printf("\nExtra Section: \n")
+	for _, x := range m.Extra {
+		fmt.Printf("%v\n", x)
+	}
+	fmt.Printf("\n")
+}
+
+func TestCheckZSKverifiability(t *testing.T) {
+	fqdn := "bund.de"
+	x := New(Options{}).checkZSKverifiability(context.Background(), fqdn)
+	t.Log(x)
+}
+
+func TestCheckPath(t *testing.T) {
+	r := Result{}
+	New(Options{}).checkPath(context.Background(), &r, "bsi.de")
+}
+
+func TestBsiDE(t *testing.T) {
+	in := New(Options{})
+	m := in.dnssecQuery(context.Background(), "bsi.de", dns.TypeANY, in.resolver)
+	if &m == nil {
+		t.Error("No response from dnssecQuery()")
+	}
+	for _, x := range m.Answer {
+		fmt.Printf("%v", x)
+	}
+	for _, x := range m.Ns {
+		fmt.Printf("%v", x)
+	}
+	for _, x := range m.Extra {
+		fmt.Printf("%v", x)
+	}
+}
+
+func TestGetDS(t *testing.T) {
+	in := New(Options{})
+	m := in.dnssecQuery(context.Background(), "bsi.de", dns.TypeDS, in.resolver)
+	for _, x := range m.Answer {
+		fmt.Printf("%v\n", x)
+	}
+	for _, x := range m.Ns {
+		fmt.Printf("%v\n", x)
+	}
+	for _, x := range m.Extra {
+		fmt.Printf("%v\n", x)
+	}
+}
+
+/*func TestGetKSK(t *testing.T) {
+	m := getDNSKEYs("bsi.de", 257)
+	fmt.Printf("%v\n", m[0].KeyTag())
+}*/
+
+/*func TestMakeZones(t *testing.T) {
+	y := makeZones("example.higher.tld")
+	for _, x := range y {
+		fmt.Printf(x)
+		//	t.Log(x)
+	}
+}*/
+
+func TestMakeQuery(t *testing.T) {
+	in := New(Options{})
+	m := in.dnssecQuery(context.Background(), "bsi.de", dns.TypeDNSKEY, in.resolver)
+	for _, x := range m.Answer {
+		fmt.Printf("%v\n", x)
+	}
+}
+
+func TestListOfDNSresolvers(t *testing.T) {
+	servers := []string{"185.48.116.10", "185.48.118.6", "8.8.8.8", "8.8.4.4", "9.9.9.10", "4.2.2.1", "4.2.2.2", "4.2.2.3"}
+	results := make([]Result, len(servers))
+	for i := range servers {
+		in := New(Options{Resolver: &ServerResolver{Servers: []string{servers[i]}}})
+		in.checkPath(context.Background(), &results[i], "bund.de")
+	}
+}
+
+// failingResolver fails every exchange
+type failingResolver struct{}
+
+func (failingResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	return nil, fmt.Errorf("unreachable")
+}
+
+func TestDnssecQueryFailure(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnssec-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	in := New(Options{Resolver: failingResolver{}, Cache: dir})
+	m := in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver)
+	if m.Rcode != dns.RcodeServerFailure || len(m.Answer) != 0 {
+		t.Errorf("Unexpected response %v", m)
+	}
+	// The failure is recorded for replays only
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 || !strings.HasSuffix(files[0].Name(), failureSuffix) {
+		t.Fatalf("Failed query was cached")
+	}
+	in = New(Options{Resolver: newFakeResolver(t, "example. 3600 IN DNSKEY 256 3 13 AAAA"), Cache: dir})
+	if m := in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver); len(m.Answer) != 1 {
+		t.Errorf("Failure reused: %v", m)
+	}
+}
+
+// dohResolver is a fakeResolver named like a DNS over HTTPS server
+type dohResolver struct {
+	*fakeResolver
+}
+
+func (dohResolver) String() string {
+	return "https://dns.example/dns-query"
+}
+
+func TestDnssecQueryCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnssec-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fake := newFakeResolver(t, "example. 3600 IN DNSKEY 257 3 13 AA==")
+	in := New(Options{Resolver: dohResolver{fake}, Cache: dir})
+	in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver)
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Response not cached, cache holds %d files", len(files))
+	}
+	m := in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, failingResolver{})
+	if len(m.Answer) != 0 {
+		t.Errorf("Cache of another resolver used")
+	}
+	fake.rrs = nil
+	if m = in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver); len(m.Answer) != 1 {
+		t.Errorf("Cached response not used: %v", m)
+	}
+}
+
+func TestCacheReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnssec-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	fake := newFakeResolver(t, "example. 3600 IN DNSKEY 257 3 13 AA==")
+	in := New(Options{Resolver: fake, Cache: dir})
+	in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver)
+	old := time.Now().Add(-2 * cacheMaxAge)
+	if err := os.Chtimes(in.cacheFile("example", dns.TypeDNSKEY, fake), old, old); err != nil {
+		t.Fatal(err)
+	}
+	fake.rrs = nil
+	in = New(Options{Resolver: fake, Cache: dir, Replay: true})
+	if m := in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver); len(m.Answer) != 1 {
+		t.Errorf("Stale cache file not replayed: %v", m)
+	}
+	// Evaluating at another time alone does not reuse stale files
+	in = New(Options{Resolver: fake, Cache: dir, Time: old})
+	if m := in.dnssecQuery(context.Background(), "example", dns.TypeDNSKEY, in.resolver); len(m.Answer) != 0 {
+		t.Errorf("Stale cache file used without replay: %v", m)
+	}
+}
@@ -0,0 +1,94 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// Names of the DS digest types (RFC 3658, RFC 4509, RFC 5933, RFC 6605) as
+// used for the hash functions of the policies
+var digestNames = map[uint8]string{
+	dns.SHA1:   "SHA-1",
+	dns.SHA256: "SHA-256",
+	dns.GOST94: "GOST R 34.11-94",
+	dns.SHA384: "SHA-384",
+}
+
+// Lists the DS RRs of a zone at its parent with the verdicts of the policies
+// for their digest types and whether they match a published DNSKEY.
+func (in *Inspector) checkDS(ctx context.Context, z *Zone) {
+	if z.FQDN == "." {
+		return
+	}
+	ds, _, _, _ := in.getDSset(ctx, z.FQDN)
+	if len(ds) == 0 {
+		return
+	}
+	keys, _ := in.getDNSKEYset(ctx, z.FQDN)
+	z.DS, z.DSIssues = evaluateDS(ds, keys, in.policies, in.now().Year())
+}
+
+// dsSHA1Only is the DS issue of a DS RRset with SHA-1 digests only
+const dsSHA1Only = "all DS RRs use SHA-1 digests"
+
+// Evaluates a DS RRset against the DNSKEY RRset of the child zone. DS RRs
+// matching no DNSKEY (orphaned) and DS RRsets with SHA-1 digests only are
+// reported as issues. The digest types are rated as of year.
+func evaluateDS(ds []dns.RR, keys []*dns.DNSKEY, policies []*Policy, year int) (ret []DSRecord, issues []string) {
+	sha1Only := true
+	for _, rr := range ds {
+		d, ok := rr.(*dns.DS)
+		if !ok {
+			continue
+		}
+		r := DSRecord{
+			KeyTag:     d.KeyTag,
+			Algorithm:  dns.AlgorithmToString[d.Algorithm],
+			DigestType: digestNames[d.DigestType],
+			Digest:     d.Digest,
+		}
+		if r.DigestType == "" {
+			r.DigestType = fmt.Sprintf("unknown (%d)", d.DigestType)
+		}
+		for _, k := range keys {
+			if dsMatchesKey(d, k) {
+				r.MatchesKey = true
+			}
+		}
+		if !r.MatchesKey {
+			issues = append(issues, fmt.Sprintf("DS RR %d (%s) matches no published DNSKEY", d.KeyTag, r.DigestType))
+		}
+		if d.DigestType != dns.SHA1 {
+			sha1Only = false
+		}
+		for _, p := range policies {
+			c := Compliance{Policy: p.String()}
+			c.HComment, c.HUntil = p.hash(r.DigestType, year)
+			if r.Policy == "" {
+				r.Policy = c.Policy
+				r.HComment, r.HUntil = c.HComment, c.HUntil
+			}
+			r.Compliance = append(r.Compliance, c)
+		}
+		ret = append(ret, r)
+	}
+	if len(ret) > 0 && sha1Only {
+		issues = append(issues, dsSHA1Only)
+	}
+	return
+}
+
+// Returns the DS RRs with SHA-256 and SHA-384 digests of keys in presentation
+// format
+func dsRecords(keys []*dns.DNSKEY) (ret []string) {
+	for _, k := range keys {
+		for _, h := range []uint8{dns.SHA256, dns.SHA384} {
+			if ds := k.ToDS(h); ds != nil {
+				ret = append(ret, ds.String())
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,39 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestEvaluateDS(t *testing.T) {
+	policies, err := LoadPolicies([]string{"bsi", "rfc8624"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ksk := newTestZone(t, "example.")
+	orphan := newTestZone(t, "example.")
+	sha1 := ksk.key.ToDS(dns.SHA1)
+	sha256 := ksk.key.ToDS(dns.SHA256)
+	orphaned := orphan.key.ToDS(dns.SHA256)
+
+	ds, issues := evaluateDS([]dns.RR{sha1, sha256, orphaned}, []*dns.DNSKEY{ksk.key}, policies, 2020)
+	if len(ds) != 3 {
+		t.Fatalf("got %d DS records, want 3", len(ds))
+	}
+	if ds[0].DigestType != "SHA-1" || !ds[0].MatchesKey || ds[0].HComment != "NON-COMPLIANT" ||
+		len(ds[0].Compliance) != 2 || ds[0].Compliance[1].HComment != "MUST NOT" {
+		t.Errorf("SHA-1 DS: got %+v", ds[0])
+	}
+	if ds[1].DigestType != "SHA-256" || !ds[1].MatchesKey || ds[1].HComment != "COMPLIANT" || ds[1].Compliance[1].HComment != "MUST" {
+		t.Errorf("SHA-256 DS: got %+v", ds[1])
+	}
+	if ds[2].MatchesKey || len(issues) != 1 {
+		t.Errorf("orphaned DS: got %+v, issues %v", ds[2], issues)
+	}
+
+	_, issues = evaluateDS([]dns.RR{sha1}, []*dns.DNSKEY{ksk.key}, policies, 2020)
+	if len(issues) != 1 || issues[0] != "all DS RRs use SHA-1 digests" {
+		t.Errorf("SHA-1 only: got issues %v", issues)
+	}
+}
@@ -0,0 +1,109 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/miekg/dns"
+)
+
+// DefaultWalkLimit is the default for Options.WalkLimit
+const DefaultWalkLimit = 1000
+
+// Enumeration risks
+const (
+	// NSEC RRs name the next existing name, the zone can be listed by
+	// following the chain
+	RiskHigh = "high"
+	// NSEC3 RRs expose hashes of the names that can be collected and
+	// guessed offline
+	RiskMedium = "medium"
+)
+
+// Reports how far the contents of a signed zone can be enumerated from its
+// denial of existence. For NSEC the chain is walked if Options.WalkNSEC is
+// set, for NSEC3 the hash parameters are reported.
+func (in *Inspector) checkEnumeration(ctx context.Context, z *Zone) {
+	if z.Denial == nil {
+		return
+	}
+	m := in.dnssecQuery(ctx, z.nxdomainProbe(), dns.TypeA, in.auth)
+	nsec, nsec3 := splitDenialRRs(m.Ns)
+	e := &Enumeration{}
+	switch {
+	case len(nsec3) > 0:
+		nsec3Exposure(e, nsec3)
+	case len(nsec) > 0:
+		e.Method = "NSEC"
+		e.Risk = RiskHigh
+		if in.opts.WalkNSEC {
+			in.walkNSEC(ctx, z, e)
+		}
+	default:
+		return
+	}
+	z.Enumeration = e
+}
+
+// Reports the parameters of NSEC3 RRs
+func nsec3Exposure(e *Enumeration, nsec3 []*dns.NSEC3) {
+	n := nsec3[0]
+	e.Method = "NSEC3"
+	e.Risk = RiskMedium
+	e.HashAlgorithm = fmt.Sprintf("unknown (%d)", n.Hash)
+	if n.Hash == dns.SHA1 {
+		e.HashAlgorithm = "SHA-1"
+	}
+	e.SaltLength = int(n.SaltLength)
+	e.Iterations = int(n.Iterations)
+	for _, n := range nsec3 {
+		if n.Flags&1 != 0 {
+			e.OptOut = true
+		}
+	}
+}
+
+// Follows the NSEC chain from the apex by asking the authoritative
+// nameservers for the NSEC RR of each name until the chain returns to the
+// apex or Options.WalkLimit names are found.
+func (in *Inspector) walkNSEC(ctx context.Context, z *Zone, e *Enumeration) {
+	var servers []string
+	for i := range z.AutoritativeNS {
+		servers = append(servers, z.AutoritativeNS[i].reachableAddresses()...)
+	}
+	r := in.auth
+	if len(servers) > 0 {
+		r = in.server(servers...)
+	}
+	limit := in.opts.WalkLimit
+	if limit <= 0 {
+		limit = DefaultWalkLimit
+	}
+	e.Walked = true
+	apex := dns.Fqdn(z.FQDN)
+	name := apex
+	for len(e.Names) < limit && ctx.Err() == nil {
+		m := in.dnssecQuery(ctx, name, dns.TypeNSEC, r)
+		var n *dns.NSEC
+		for _, rr := range m.Answer {
+			if t, ok := rr.(*dns.NSEC); ok && equalNames(t.Hdr.Name, name) {
+				n = t
+			}
+		}
+		if n == nil {
+			e.WalkError = fmt.Sprintf("no NSEC RR for %s", name)
+			break
+		}
+		e.Names = append(e.Names, name)
+		if equalNames(n.NextDomain, apex) {
+			e.Complete = true
+			break
+		}
+		if canonicalCompare(n.NextDomain, name) <= 0 {
+			e.WalkError = fmt.Sprintf("NSEC RR of %s does not advance the chain", name)
+			break
+		}
+		name = dns.Fqdn(n.NextDomain)
+	}
+	e.ExposedNames = len(e.Names)
+}
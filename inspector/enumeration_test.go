@@ -0,0 +1,68 @@
+package inspector
+
+import (
+	"context"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestWalkNSEC(t *testing.T) {
+	chain := map[string]dns.RR{}
+	for _, rr := range mustRRs(t,
+		"example. 3600 IN NSEC a.example. NS SOA RRSIG NSEC DNSKEY",
+		"a.example. 3600 IN NSEC mail.example. A RRSIG NSEC",
+		"mail.example. 3600 IN NSEC www.example. MX RRSIG NSEC",
+		"www.example. 3600 IN NSEC example. A RRSIG NSEC",
+	) {
+		chain[rr.Header().Name] = rr
+	}
+	addr, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		if rr, ok := chain[r.Question[0].Name]; ok && r.Question[0].Qtype == dns.TypeNSEC {
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+
+	z := &Zone{FQDN: "example", AutoritativeNS: []Nameserver{{Addresses: []Address{{IP: addr, Reachable: true}}}}}
+	in := New(Options{Resolver: newFakeResolver(t)})
+	e := &Enumeration{}
+	in.walkNSEC(context.Background(), z, e)
+	if !e.Complete || e.ExposedNames != 4 || e.WalkError != "" || e.Names[2] != "mail.example." {
+		t.Errorf("got %+v", e)
+	}
+
+	in = New(Options{Resolver: newFakeResolver(t), WalkLimit: 2})
+	e = &Enumeration{}
+	in.walkNSEC(context.Background(), z, e)
+	if e.Complete || e.ExposedNames != 2 {
+		t.Errorf("limited walk: got %+v", e)
+	}
+
+	delete(chain, "mail.example.")
+	in = New(Options{Resolver: newFakeResolver(t)})
+	e = &Enumeration{}
+	in.walkNSEC(context.Background(), z, e)
+	if e.Complete || e.ExposedNames != 2 || e.WalkError != "no NSEC RR for mail.example." {
+		t.Errorf("broken chain: got %+v", e)
+	}
+}
+
+func TestNSEC3Exposure(t *testing.T) {
+	var nsec3 []*dns.NSEC3
+	for _, rr := range mustRRs(t,
+		"2vptu5timamqttgl4luu9kg21e0aor3s.example. 3600 IN NSEC3 1 0 10 aabbccdd 2vptu5timamqttgl4luu9kg21e0aor3t A",
+		"2vptu5timamqttgl4luu9kg21e0aor3t.example. 3600 IN NSEC3 1 1 10 aabbccdd 2vptu5timamqttgl4luu9kg21e0aor3s NS",
+	) {
+		nsec3 = append(nsec3, rr.(*dns.NSEC3))
+	}
+	e := &Enumeration{}
+	nsec3Exposure(e, nsec3)
+	if e.Method != "NSEC3" || e.Risk != RiskMedium || e.HashAlgorithm != "SHA-1" || e.SaltLength != 4 || e.Iterations != 10 || !e.OptOut {
+		t.Errorf("got %+v", e)
+	}
+}
@@ -0,0 +1,197 @@
+package inspector
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severities of findings
+const (
+	// The zone or target does not validate or could not be checked
+	SeverityCritical = "critical"
+	// A misconfiguration that breaks or weakens DNSSEC
+	SeverityError = "error"
+	// A risk or a deviation from recommendations
+	SeverityWarning = "warning"
+	// A fact worth knowing that needs no action
+	SeverityInfo = "info"
+)
+
+// Codes of findings
+const (
+	CodeServerUnreachable      = "SERVER_UNREACHABLE"
+	CodeBogus                  = "BOGUS"
+	CodeInsecure               = "INSECURE"
+	CodeTrustIsland            = "TRUST_ISLAND"
+	CodeValidation             = "VALIDATION_FAILED"
+	CodeMalformedKey           = "MALFORMED_KEY"
+	CodeKeyNonCompliant        = "KEY_NON_COMPLIANT"
+	CodeKSKWithoutDS           = "KSK_WITHOUT_DS"
+	CodeSignatureValidity      = "SIGNATURE_VALIDITY"
+	CodeDenial                 = "DENIAL_OF_EXISTENCE"
+	CodeZoneEnumeration        = "ZONE_ENUMERATION"
+	CodeNSEC3Parameters        = "NSEC3_PARAMETERS"
+	CodeDS                     = "DS"
+	CodeCDS                    = "CDS"
+	CodeRollover               = "ROLLOVER"
+	CodeInconsistent           = "INCONSISTENT_NAMESERVERS"
+	CodeNameserverValidation   = "NAMESERVER_VALIDATION"
+	CodeOpenResolver           = "OPEN_RESOLVER"
+	CodeRALeak                 = "RA_LEAK"
+	CodeFragmentation          = "FRAGMENTATION_RISK"
+	CodeServerViewInconsistent = "SERVER_VIEW_INCONSISTENT"
+)
+
+// Finding is a single problem found in an audit
+type Finding struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	// Zone the finding belongs to, empty for the whole audit
+	Zone string `json:"zone,omitempty"`
+	// Record identifies the RR, key or server concerned
+	Record  string `json:"record,omitempty"`
+	Message string `json:"message"`
+	// RFC names the specification the finding refers to
+	RFC string `json:"rfc,omitempty"`
+}
+
+type findings []Finding
+
+func (f *findings) add(code, severity, zone, record, msg, rfc string) {
+	*f = append(*f, Finding{Code: code, Severity: severity, Zone: zone, Record: record, Message: msg, RFC: rfc})
+}
+
+// Collects the problems reported in the fields of a result as findings, so
+// they can be processed without knowing every check
+func collectFindings(res *Result) []Finding {
+	var f findings
+	for _, s := range res.ServerFailures {
+		if s.Unreachable {
+			f.add(CodeServerUnreachable, SeverityError, "", s.Server,
+				fmt.Sprintf("server unreachable after %d failed queries: %s", s.Failures, s.Error), "")
+		}
+	}
+	if res.TrustIsland {
+		f.add(CodeTrustIsland, SeverityWarning, res.TrustIslandAnchorZone, "",
+			"the zone is signed but has no DS RR in its parent, submit: "+strings.Join(res.RecommendedDS, "; "), "RFC 4035 section 4.3")
+	}
+	for _, v := range res.Servers {
+		for _, d := range v.Differences {
+			f.add(CodeServerViewInconsistent, SeverityError, "", v.Server, d, "")
+		}
+	}
+	for i := range res.Zones {
+		zoneFindings(&f, &res.Zones[i])
+	}
+	return f
+}
+
+// Collects the findings of a single zone
+func zoneFindings(f *findings, z *Zone) {
+	zone := z.FQDN
+	switch z.Status {
+	case StatusBogus:
+		f.add(CodeBogus, SeverityCritical, zone, "", z.StatusReason, "RFC 4035 section 4.3")
+	case StatusInsecure:
+		f.add(CodeInsecure, SeverityWarning, zone, "", z.StatusReason, "RFC 4035 section 4.3")
+	}
+	for _, e := range []string{z.ValidationErrorAnswer, z.ValidationErrorNs, z.ValidationErrorExtra} {
+		if e != "" {
+			f.add(CodeValidation, SeverityError, zone, "", e, "RFC 4035 section 5.3")
+		}
+	}
+	for _, k := range z.Keys {
+		record := fmt.Sprintf("%s %s", k.Type, k.Alg)
+		if k.Error != "" {
+			f.add(CodeMalformedKey, SeverityError, zone, record, k.Error, "RFC 4034 section 2")
+		}
+		for _, c := range k.Compliance {
+			if nonCompliant(c.AComment) || nonCompliant(c.HComment) {
+				f.add(CodeKeyNonCompliant, SeverityWarning, zone, record,
+					fmt.Sprintf("key of %d bits with %s is not compliant with %s", k.KeyLength, k.Hash, c.Policy), "")
+			}
+		}
+		if len(k.RecommendedDS) > 0 {
+			f.add(CodeKSKWithoutDS, SeverityWarning, zone, record,
+				"no DS RR matches the KSK, submit: "+strings.Join(k.RecommendedDS, "; "), "RFC 4035 section 5.2")
+		}
+	}
+	for _, s := range z.Signatures {
+		record := fmt.Sprintf("RRSIG %s %s %d", s.Name, s.TypeCovered, s.KeyTag)
+		for _, w := range s.Warnings {
+			severity := SeverityWarning
+			if w == "signature expired" || w == "signature is not yet valid" {
+				severity = SeverityError
+			}
+			f.add(CodeSignatureValidity, severity, zone, record, w, "RFC 4034 section 3.1.5")
+		}
+	}
+	if d := z.Denial; d != nil {
+		for _, e := range []string{d.NXDOMAINError, d.NODATAError} {
+			if e != "" {
+				f.add(CodeDenial, SeverityError, zone, d.Method, e, "RFC 4035 section 5.4")
+			}
+		}
+	}
+	if e := z.Enumeration; e != nil && e.Risk == RiskHigh {
+		f.add(CodeZoneEnumeration, SeverityInfo, zone, e.Method, "the NSEC chain exposes the names of the zone", "RFC 5155 section 1")
+	}
+	if p := z.NSEC3Parameters; p != nil {
+		for _, i := range p.Issues {
+			f.add(CodeNSEC3Parameters, SeverityWarning, zone, p.Param, i, "RFC 9276 section 3.1")
+		}
+	}
+	for _, i := range z.DSIssues {
+		// Every other issue is a DS RR matching no DNSKEY
+		rfc := "RFC 4035 section 5.2"
+		if i == dsSHA1Only {
+			rfc = "RFC 8624 section 3.3"
+		}
+		f.add(CodeDS, SeverityWarning, zone, "", i, rfc)
+	}
+	if c := z.CDS; c != nil {
+		for _, i := range c.Issues {
+			f.add(CodeCDS, SeverityWarning, zone, "", i, "RFC 7344 section 4.1")
+		}
+	}
+	if r := z.Rollover; r != nil {
+		for _, i := range r.Issues {
+			f.add(CodeRollover, SeverityWarning, zone, "", i, "RFC 7583")
+		}
+	}
+	for _, i := range z.Inconsistencies {
+		f.add(CodeInconsistent, SeverityError, zone, "", i, "")
+	}
+	for _, n := range z.AutoritativeNS {
+		for _, a := range n.Addresses {
+			if a.ValidationError != "" {
+				f.add(CodeNameserverValidation, SeverityError, zone, n.Name+" "+a.IP, a.ValidationError, "")
+			}
+			if !a.Reachable {
+				msg := "nameserver address unreachable"
+				if a.Error != "" {
+					msg += ": " + a.Error
+				}
+				f.add(CodeServerUnreachable, SeverityError, zone, n.Name+" "+a.IP, msg, "")
+			}
+			if a.Resolver {
+				f.add(CodeOpenResolver, SeverityWarning, zone, n.Name+" "+a.IP,
+					"authoritative nameserver answers recursive queries", "RFC 5358")
+			}
+			if a.RALeak {
+				f.add(CodeRALeak, SeverityWarning, zone, n.Name+" "+a.IP,
+					"authoritative answers have the RA bit set, recursion seems to be enabled", "RFC 1035 section 4.1.1")
+			}
+		}
+	}
+	if s := z.ResponseSizes; s != nil {
+		for _, i := range s.Issues {
+			f.add(CodeFragmentation, SeverityWarning, zone, s.Server, i, "RFC 9715")
+		}
+	}
+}
+
+// Checks if a policy verdict rules out the use of an algorithm or hash
+func nonCompliant(verdict string) bool {
+	return verdict == "NON-COMPLIANT" || verdict == "MUST NOT"
+}
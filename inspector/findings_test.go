@@ -0,0 +1,60 @@
+package inspector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCollectFindings(t *testing.T) {
+	res := &Result{
+		ServerFailures: []ServerFailure{{Server: "192.0.2.1:53", Failures: 3, Unreachable: true, Error: "timeout"}},
+		Zones: []Zone{{
+			FQDN:         "example",
+			Status:       StatusBogus,
+			StatusReason: "DNSKEY RRset not signed",
+			Keys: []Key{
+				{Type: "KSK", Alg: "RSA", Error: "RSA key has no modulus"},
+				{Type: "ZSK", Alg: "RSA", KeyLength: 1024, AComment: "COMPLIANT", Policy: "BSI TR-02102-1 (2025-01)",
+					Compliance: []Compliance{
+						{Policy: "BSI TR-02102-1 (2025-01)", AComment: "COMPLIANT"},
+						{Policy: "RFC 8624", AComment: "MUST NOT"},
+						{Policy: "custom", HComment: "NON-COMPLIANT"},
+					}},
+			},
+			Signatures: []Signature{{Name: "example.", TypeCovered: "SOA", KeyTag: 1, Warnings: []string{"signature expired"}}},
+			DSIssues:   []string{"DS RR 1 (SHA-1) matches no published DNSKEY", dsSHA1Only},
+			AutoritativeNS: []Nameserver{{Name: "ns.example.", Addresses: []Address{
+				{IP: "192.0.2.53", Reachable: true, RALeak: true},
+			}}},
+		}},
+	}
+	want := []struct{ code, severity, zone string }{
+		{CodeServerUnreachable, SeverityError, ""},
+		{CodeBogus, SeverityCritical, "example"},
+		{CodeMalformedKey, SeverityError, "example"},
+		{CodeKeyNonCompliant, SeverityWarning, "example"},
+		{CodeKeyNonCompliant, SeverityWarning, "example"},
+		{CodeSignatureValidity, SeverityError, "example"},
+		{CodeDS, SeverityWarning, "example"},
+		{CodeDS, SeverityWarning, "example"},
+		{CodeRALeak, SeverityWarning, "example"},
+	}
+	got := collectFindings(res)
+	if len(got) != len(want) {
+		t.Fatalf("Got findings %+v", got)
+	}
+	for i, f := range got {
+		if f.Code != want[i].code || f.Severity != want[i].severity || f.Zone != want[i].zone || f.Message == "" {
+			t.Errorf("Finding %d: got %+v, want %+v", i, f, want[i])
+		}
+	}
+	if !strings.HasSuffix(got[3].Message, "RFC 8624") || !strings.HasSuffix(got[4].Message, "custom") {
+		t.Errorf("Unexpected policies in %q and %q", got[3].Message, got[4].Message)
+	}
+	if got[6].RFC != "RFC 4035 section 5.2" || got[7].RFC != "RFC 8624 section 3.3" {
+		t.Errorf("Unexpected references of DS findings %+v", got[6:8])
+	}
+	if got[8].Record != "ns.example. 192.0.2.53" {
+		t.Errorf("Unexpected RA leak finding %+v", got[8])
+	}
+}
@@ -0,0 +1,126 @@
+package inspector
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultRetries is the default for Options.Retries
+	DefaultRetries = 2
+	// DefaultBackoff is the default for Options.Backoff
+	DefaultBackoff = 250 * time.Millisecond
+	// maxConsecutiveFailures marks a server unreachable
+	maxConsecutiveFailures = 3
+	// unreachableRetry is the time an unreachable server is skipped
+	unreachableRetry = 5 * time.Minute
+)
+
+// ServerFailure counts the failed exchanges with a server
+type ServerFailure struct {
+	Server   string `json:"server"`
+	Failures int    `json:"failures"`
+	// Unreachable is set after several consecutive failures. The server is
+	// skipped for a while then.
+	Unreachable bool   `json:"unreachable"`
+	Error       string `json:"error"`
+
+	consecutive int
+	last        time.Time
+}
+
+// ServerHealth tracks failed exchanges per server, so servers failing
+// repeatedly are skipped instead of waiting for their timeout on every
+// query. It is safe for concurrent use; a nil ServerHealth tracks nothing.
+type ServerHealth struct {
+	mu      sync.Mutex
+	servers map[string]*ServerFailure
+}
+
+type healthKey struct{}
+
+// Returns a context tracking the failures of the servers queried with it in
+// h, so every audit counts only its own failures
+func withHealth(ctx context.Context, h *ServerHealth) context.Context {
+	return context.WithValue(ctx, healthKey{}, h)
+}
+
+// Returns the ServerHealth of p or else the one tracking the audit of ctx
+func (p RetryPolicy) health(ctx context.Context) *ServerHealth {
+	if p.Health != nil {
+		return p.Health
+	}
+	h, _ := ctx.Value(healthKey{}).(*ServerHealth)
+	return h
+}
+
+// Records the outcome of an exchange with server
+func (h *ServerHealth) record(server string, err error) {
+	if h == nil {
+		return
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, ok := h.servers[server]
+	if err == nil {
+		if ok {
+			f.consecutive, f.Unreachable = 0, false
+		}
+		return
+	}
+	if h.servers == nil {
+		h.servers = map[string]*ServerFailure{}
+	}
+	if !ok {
+		f = &ServerFailure{Server: server}
+		h.servers[server] = f
+	}
+	f.Failures++
+	f.consecutive++
+	f.Error = err.Error()
+	f.last = time.Now()
+	if f.consecutive >= maxConsecutiveFailures {
+		f.Unreachable = true
+	}
+}
+
+// Checks if server failed too often recently to be queried
+func (h *ServerHealth) skip(server string) bool {
+	if h == nil {
+		return false
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	f, ok := h.servers[server]
+	return ok && f.Unreachable && time.Since(f.last) < unreachableRetry
+}
+
+// Failures returns the servers with failed exchanges, sorted by address
+func (h *ServerHealth) Failures() []ServerFailure {
+	if h == nil {
+		return nil
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	var ret []ServerFailure
+	for _, f := range h.servers {
+		ret = append(ret, *f)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i].Server < ret[j].Server })
+	return ret
+}
+
+// Waits before retry attempt n (starting at 1), doubling the delay for
+// every attempt. Returns false if ctx is done first.
+func backoff(ctx context.Context, delay time.Duration, n int) bool {
+	t := time.NewTimer(delay << uint(n-1))
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
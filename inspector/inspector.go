@@ -0,0 +1,280 @@
+/*
+Package inspector audits the DNSSEC configuration of a domain name.
+
+An Inspector walks the chain from the given name up to the root zone and
+checks for each zone the existence and compliance of DNSKEY RRs, the use of
+NSEC3, the validation of signed RRs and the authoritative nameservers.
+
+	in := inspector.New(inspector.Options{Cache: "/var/cache/dnssec"})
+	res, err := in.Inspect(ctx, "example.com")
+*/
+package inspector
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Log levels
+var (
+	Info    = log.New(ioutil.Discard, "INFO: ", log.Ldate|log.Ltime|log.Lshortfile)
+	Warning = log.New(ioutil.Discard, "WARNING: ", log.Ldate|log.Ltime|log.Lshortfile)
+	Error   = log.New(os.Stderr, "ERROR: ", log.Ldate|log.Ltime|log.Lshortfile)
+)
+
+// cacheMaxAge is the time a cached query result is reused
+const cacheMaxAge = 3600 * time.Second
+
+// DefaultResolverProbes are used if Options.ResolverProbes is empty
+var DefaultResolverProbes = []string{"google.com", "bund.de"}
+
+// DefaultExpiryWarning is the default for Options.ExpiryWarning
+const DefaultExpiryWarning = 7 * 24 * time.Hour
+
+// Options configures an Inspector
+type Options struct {
+	// Resolver is used for recursive lookups. If nil the nameservers from
+	// /etc/resolv.conf are used. A supplied resolver is not modified and
+	// keeps its own retries, see Options.RetryPolicy. An IterativeResolver
+	// also answers the queries to the authoritative nameservers and records
+	// the referrals to the target in Result.Referrals.
+	Resolver Resolver
+	// Cache is a directory to cache query results in. Empty disables caching.
+	Cache string
+	// Replay uses cached responses regardless of their age and keeps stale
+	// cache files, so a recorded audit can be repeated, e.g. at Time. No
+	// query is sent, the audit fails if a response is missing in the cache.
+	Replay bool
+	// Timeout bounds a single DNS exchange. Zero uses the dns package default.
+	Timeout time.Duration
+	// Retries is the number of further attempts per server after a failed
+	// exchange, waiting Backoff before the first and doubling the delay for
+	// each further one. Zero uses DefaultRetries and DefaultBackoff, a
+	// negative value disables retries. They apply to the resolvers the
+	// Inspector creates.
+	Retries int
+	Backoff time.Duration
+	// ResolverProbes are names outside of the audited zones used to test if
+	// authoritative nameservers act as resolvers. The first name not
+	// belonging to the zone is used. Defaults to DefaultResolverProbes.
+	ResolverProbes []string
+	// Policies the keys are evaluated against. Every key lists its verdict
+	// for each of them; the first one also fills the verdict fields of Key.
+	// If empty the built-in DefaultPolicy is used.
+	Policies []*Policy
+	// ExpiryWarning flags signatures expiring within this duration. Zero
+	// uses DefaultExpiryWarning.
+	ExpiryWarning time.Duration
+	// Time the signatures, policies and expiration forecasts are evaluated
+	// at. Zero uses the current time.
+	Time time.Time
+	// WalkNSEC enables walking the NSEC chain of zones using NSEC to count
+	// the names exposed. At most WalkLimit names are queried, zero uses
+	// DefaultWalkLimit.
+	WalkNSEC  bool
+	WalkLimit int
+	// TrustAnchors are DS or DNSKEY RRs the chain of trust is validated
+	// against, e.g. loaded with LoadTrustAnchorsAt for Time. If empty the
+	// built-in RootAnchors are used.
+	TrustAnchors []dns.RR
+	// CompareServers compares the DNSSEC RRsets served by each server of a
+	// ServerResolver with more than one server (see ServerView)
+	CompareServers bool
+}
+
+// RetryPolicy returns the retries of the resolvers the Inspector creates,
+// so a ServerResolver or IterativeResolver passed as Resolver can be
+// configured the same way.
+func (o Options) RetryPolicy() RetryPolicy {
+	p := RetryPolicy{Retries: o.Retries, Backoff: o.Backoff}
+	if p.Retries == 0 {
+		p.Retries = DefaultRetries
+	} else if p.Retries < 0 {
+		p.Retries = 0
+	}
+	if p.Backoff == 0 {
+		p.Backoff = DefaultBackoff
+	}
+	return p
+}
+
+// Inspector runs DNSSEC audits. It is safe to run several audits with the
+// same Inspector.
+type Inspector struct {
+	opts     Options
+	resolver Resolver
+	auth     Resolver
+	retry    RetryPolicy
+	anchors  []dns.RR
+	policies []*Policy
+}
+
+// InitLog routes the Info and Warning logs to stdout depending on the
+// requested verbosity. Both are discarded by default.
+func InitLog(verbose bool, superverbose bool) {
+	Warning.SetOutput(ioutil.Discard)
+	Info.SetOutput(ioutil.Discard)
+	if verbose || superverbose {
+		Warning.SetOutput(os.Stdout)
+	}
+	if superverbose {
+		Info.SetOutput(os.Stdout)
+	}
+}
+
+// New returns an Inspector for the given options. A cache directory that
+// does not exist disables caching; stale files in an existing one are removed.
+func New(opts Options) *Inspector {
+	in := &Inspector{opts: opts, resolver: opts.Resolver, retry: opts.RetryPolicy()}
+	if in.resolver == nil {
+		s, err := NewSystemResolver()
+		if err != nil {
+			Error.Printf("Cannot read system resolver configuration: %s\n", err)
+			s = &ServerResolver{}
+		}
+		s.Timeout = opts.Timeout
+		s.RetryPolicy = in.retry
+		in.resolver = s
+	}
+	in.auth = &AuthoritativeResolver{Lookup: in.resolver, Timeout: opts.Timeout, RetryPolicy: in.retry}
+	if _, ok := in.resolver.(*IterativeResolver); ok {
+		// Answers of the iterative resolver are authoritative already
+		in.auth = in.resolver
+	}
+	if len(in.opts.ResolverProbes) == 0 {
+		in.opts.ResolverProbes = DefaultResolverProbes
+	}
+	if in.opts.ExpiryWarning == 0 {
+		in.opts.ExpiryWarning = DefaultExpiryWarning
+	}
+	in.policies = opts.Policies
+	if len(in.policies) == 0 {
+		p, err := LoadPolicy(DefaultPolicy)
+		if err != nil {
+			Error.Printf("Cannot load built-in policy: %s\n", err)
+			p = &Policy{}
+		}
+		in.policies = []*Policy{p}
+	}
+	in.anchors = opts.TrustAnchors
+	if len(in.anchors) == 0 {
+		in.anchors = rootAnchors()
+	}
+	if opts.Cache != "" {
+		if _, err := os.Stat(opts.Cache); err != nil {
+			Warning.Printf("Cache directory: %s does not exist\n", opts.Cache)
+			in.opts.Cache = ""
+		} else if !opts.Replay {
+			in.pruneCache()
+		}
+	}
+	return in
+}
+
+// Inspect audits the DNSSEC configuration of fqdn and every zone above it.
+// Problems are reported as findings of the result instead of errors. If ctx
+// is cancelled the partial result is returned along with ctx.Err(). When
+// replaying no query is sent, the partial result is returned with an error
+// if responses are missing in the cache.
+func (in *Inspector) Inspect(ctx context.Context, fqdn string) (*Result, error) {
+	if fqdn == "" {
+		return nil, errors.New("no domain name given")
+	}
+	var misses *replayMisses
+	if in.opts.Replay {
+		if in.opts.Cache == "" {
+			return nil, errors.New("replaying needs an existing cache directory")
+		}
+		misses = &replayMisses{}
+		ctx = withReplay(ctx, misses)
+	}
+	res := &Result{Target: fqdn, EvaluatedAt: in.now().Format(time.RFC3339)}
+	health := &ServerHealth{}
+	ctx = withHealth(ctx, health)
+	if r, ok := in.resolver.(*IterativeResolver); ok {
+		var err error
+		if res.Referrals, err = in.trace(ctx, r, fqdn); err != nil {
+			Warning.Printf("Iterative resolution of %s failed: %s\n", fqdn, err)
+		}
+	}
+	in.checkExistence(ctx, res, fqdn)
+	in.checkPath(ctx, res, fqdn)
+	if sr, ok := in.resolver.(*ServerResolver); ok && in.opts.CompareServers && len(sr.Servers) > 1 {
+		res.Servers = in.compareServers(ctx, res, sr.Servers)
+	}
+	res.ServerFailures = health.Failures()
+	res.Findings = collectFindings(res)
+	if err := misses.err(); err != nil {
+		return res, err
+	}
+	return res, ctx.Err()
+}
+
+// Returns the time the zones are evaluated at
+func (in *Inspector) now() time.Time {
+	if !in.opts.Time.IsZero() {
+		return in.opts.Time.UTC()
+	}
+	return time.Now().UTC()
+}
+
+// Checks if a cache file may be used. Any file is used when replaying.
+func (in *Inspector) cacheValid(info os.FileInfo) bool {
+	return in.opts.Replay || time.Since(info.ModTime()) < cacheMaxAge
+}
+
+// Removes all files from the cache directory that are older than cacheMaxAge
+func (in *Inspector) pruneCache() {
+	cacheDir, err := os.Open(in.opts.Cache)
+	if err != nil {
+		Error.Printf("Cannot open cache directory: %s\n", in.opts.Cache)
+		return
+	}
+	defer cacheDir.Close()
+	files, err := cacheDir.Readdirnames(0)
+	if err != nil {
+		Error.Printf("Cannot read cache directory content\n")
+	}
+	for _, f := range files {
+		p := filepath.Join(in.opts.Cache, f)
+		info, err := os.Stat(p)
+		if err != nil {
+			Warning.Printf("Cannot get stats for file: %s\n", f)
+		} else if time.Since(info.ModTime()) > cacheMaxAge {
+			if err := os.Remove(p); err != nil {
+				Warning.Printf("Failed to remove file: %s\n", f)
+			} else {
+				Info.Printf("Removed cache file: %s\n", f)
+			}
+		}
+	}
+}
+
+// Returns a Resolver that sends queries to the given servers only
+func (in *Inspector) server(servers ...string) Resolver {
+	return &ServerResolver{Servers: servers, Timeout: in.opts.Timeout, RetryPolicy: in.retry}
+}
+
+// Returns an identifier for r used in log messages and cache file names
+func resolverID(r Resolver) string {
+	if s, ok := r.(fmt.Stringer); ok {
+		return s.String()
+	}
+	return fmt.Sprintf("%T", r)
+}
+
+// Returns the path of the cache file for a query sent to r. The resolver ID
+// is hashed, as server URLs contain characters not allowed in file names.
+func (in *Inspector) cacheFile(fqdn string, rrType uint16, r Resolver) string {
+	id := sha256.Sum256([]byte(resolverID(r)))
+	return filepath.Join(in.opts.Cache, fmt.Sprintf("dns_%s_%d_%x", fqdn, rrType, id[:8]))
+}
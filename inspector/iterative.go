@@ -0,0 +1,313 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RootHints are the IPv4 addresses of the root nameservers a to m
+// (https://www.internic.net/domain/named.root)
+var RootHints = []string{
+	"198.41.0.4",
+	"170.247.170.2",
+	"192.33.4.12",
+	"199.7.91.13",
+	"192.203.230.10",
+	"192.5.5.241",
+	"192.112.36.4",
+	"198.97.190.53",
+	"192.36.148.17",
+	"192.58.128.30",
+	"193.0.14.129",
+	"199.7.83.42",
+	"202.12.27.33",
+}
+
+const (
+	// maxReferrals bounds the referrals followed for a single query
+	maxReferrals = 32
+	// maxDepth bounds the nesting of CNAME chains and nameserver address
+	// lookups for delegations without glue
+	maxDepth = 8
+)
+
+// Referral is one delegation followed during an iterative resolution
+type Referral struct {
+	// Zone the referral delegates to and Parent the zone it came from, both
+	// fully qualified
+	Zone   string `json:"zone"`
+	Parent string `json:"parent"`
+	// Server of the parent zone that sent the referral
+	Server      string   `json:"server"`
+	Nameservers []string `json:"nameservers"`
+	Addresses   []string `json:"addresses"`
+	// DS RRs of the referral and DNSKEY RRs served by the nameservers of
+	// the zone, in presentation format
+	DS     []string `json:"ds,omitempty"`
+	DNSKEY []string `json:"dnskey,omitempty"`
+	Error  string   `json:"error,omitempty"`
+}
+
+// IterativeResolver resolves queries itself starting at the root
+// nameservers and following referrals, so every answer comes from the
+// authoritative nameservers of a zone instead of the cache of a recursive
+// resolver. DS queries are answered by the parent zone. Delegations are
+// cached for the TTL of their NS RRset.
+type IterativeResolver struct {
+	// RootHints are the addresses of the root nameservers. Defaults to
+	// RootHints.
+	RootHints []string
+	// Port the nameservers are queried on. Defaults to 53.
+	Port string
+	// Timeout bounds a single exchange. Zero uses the dns package default.
+	Timeout time.Duration
+	RetryPolicy
+
+	mu          sync.Mutex
+	delegations map[string]delegation
+}
+
+type delegation struct {
+	servers []string
+	expires time.Time
+}
+
+// Exchange implements Resolver
+func (r *IterativeResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	return r.resolve(ctx, m, nil, 0)
+}
+
+func (r *IterativeResolver) String() string {
+	return "iterative"
+}
+
+// Trace resolves name from the root nameservers regardless of cached
+// delegations and returns every referral followed. The DNSKEY RRset of each
+// delegated zone is queried from its nameservers.
+func (r *IterativeResolver) Trace(ctx context.Context, name string, qtype uint16) ([]Referral, error) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(name), qtype)
+	m.SetEdns0(4096, true)
+	var trace []Referral
+	_, err := r.resolve(ctx, m, &trace, 0)
+	for i := range trace {
+		q := new(dns.Msg)
+		q.SetQuestion(trace[i].Zone, dns.TypeDNSKEY)
+		q.SetEdns0(4096, true)
+		resp, _, qerr := r.query(ctx, q, trace[i].Addresses)
+		if qerr != nil {
+			trace[i].Error = qerr.Error()
+			continue
+		}
+		for _, rr := range rrsetOf(*resp, trace[i].Zone, dns.TypeDNSKEY) {
+			trace[i].DNSKEY = append(trace[i].DNSKEY, rdataString(rr))
+		}
+	}
+	return trace, err
+}
+
+// Follows the referrals for m starting at the closest known delegation or
+// the root if trace is set, recording every referral in trace
+func (r *IterativeResolver) resolve(ctx context.Context, m *dns.Msg, trace *[]Referral, depth int) (*dns.Msg, error) {
+	if len(m.Question) == 0 {
+		return nil, errors.New("query without question")
+	}
+	if depth > maxDepth {
+		return nil, errors.New("maximum resolution depth exceeded")
+	}
+	q := m.Copy()
+	q.RecursionDesired = false
+	name, qtype := dns.Fqdn(q.Question[0].Name), q.Question[0].Qtype
+	zone, servers := ".", r.rootServers()
+	if trace == nil {
+		// The parent zone is authoritative for the DS RRset
+		start := name
+		if qtype == dns.TypeDS {
+			start = parentName(name)
+		}
+		zone, servers = r.closest(start)
+	}
+	for i := 0; i < maxReferrals; i++ {
+		resp, server, err := r.query(ctx, q, servers)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %s", zone, err)
+		}
+		cut, ns, ttl := referral(resp, zone, name)
+		if cut == "" {
+			if target := cnameTarget(resp, name, qtype); target != "" {
+				next := q.Copy()
+				next.Question[0].Name = target
+				if r2, err := r.resolve(ctx, next, trace, depth+1); err == nil {
+					resp.Answer = append(resp.Answer, r2.Answer...)
+					resp.Rcode = r2.Rcode
+				}
+			}
+			return resp, nil
+		}
+		addrs := r.addresses(ctx, resp, ns, depth)
+		if trace != nil {
+			ref := Referral{Zone: cut, Parent: zone, Server: server, Nameservers: ns, Addresses: addrs}
+			for _, rr := range resp.Ns {
+				if ds, ok := rr.(*dns.DS); ok && equalNames(ds.Hdr.Name, cut) {
+					ref.DS = append(ref.DS, rdataString(ds))
+				}
+			}
+			*trace = append(*trace, ref)
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("no address for the nameservers of %s", cut)
+		}
+		r.remember(cut, addrs, ttl)
+		zone, servers = cut, addrs
+	}
+	return nil, fmt.Errorf("more than %d referrals for %s", maxReferrals, name)
+}
+
+// Sends q to the servers in order and returns the first usable response
+// and the server that sent it. Lame servers refusing the query or failing
+// are skipped, truncated responses are retried over TCP.
+func (r *IterativeResolver) query(ctx context.Context, q *dns.Msg, servers []string) (*dns.Msg, string, error) {
+	err := errors.New("no nameserver to send the query to")
+	for _, s := range servers {
+		srv := &ServerResolver{Servers: []string{s}, Timeout: r.Timeout, RetryPolicy: r.RetryPolicy}
+		resp, e := srv.Exchange(ctx, q)
+		if e != nil {
+			err = e
+			continue
+		}
+		if resp.Rcode == dns.RcodeRefused || resp.Rcode == dns.RcodeServerFailure {
+			err = fmt.Errorf("%s answered %s", s, dns.RcodeToString[resp.Rcode])
+			continue
+		}
+		return resp, s, nil
+	}
+	return nil, "", err
+}
+
+// Returns the addresses of the nameservers ns, taken from the glue of the
+// referral or else resolved iteratively
+func (r *IterativeResolver) addresses(ctx context.Context, resp *dns.Msg, ns []string, depth int) []string {
+	var v4, v6 []string
+	for _, rr := range resp.Extra {
+		for _, n := range ns {
+			if !equalNames(rr.Header().Name, n) {
+				continue
+			}
+			switch a := rr.(type) {
+			case *dns.A:
+				v4 = append(v4, r.hostPort(a.A.String()))
+			case *dns.AAAA:
+				v6 = append(v6, r.hostPort(a.AAAA.String()))
+			}
+		}
+	}
+	if len(v4)+len(v6) > 0 {
+		return append(v4, v6...)
+	}
+	for _, n := range ns {
+		for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+			m := new(dns.Msg)
+			m.SetQuestion(n, t)
+			a, err := r.resolve(ctx, m, nil, depth+1)
+			if err != nil {
+				continue
+			}
+			for _, rr := range a.Answer {
+				switch a := rr.(type) {
+				case *dns.A:
+					v4 = append(v4, r.hostPort(a.A.String()))
+				case *dns.AAAA:
+					v6 = append(v6, r.hostPort(a.AAAA.String()))
+				}
+			}
+		}
+		if len(v4)+len(v6) > 0 {
+			break
+		}
+	}
+	return append(v4, v6...)
+}
+
+// Returns the closest cached delegation enclosing name
+func (r *IterativeResolver) closest(name string) (string, []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n := strings.ToLower(name); n != "."; n = parentName(n) {
+		if d, ok := r.delegations[n]; ok && time.Now().Before(d.expires) {
+			return n, d.servers
+		}
+	}
+	return ".", r.rootServers()
+}
+
+func (r *IterativeResolver) remember(zone string, servers []string, ttl uint32) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.delegations == nil {
+		r.delegations = map[string]delegation{}
+	}
+	r.delegations[zone] = delegation{servers, time.Now().Add(time.Duration(ttl) * time.Second)}
+}
+
+func (r *IterativeResolver) rootServers() []string {
+	hints := r.RootHints
+	if len(hints) == 0 {
+		hints = RootHints
+	}
+	var ret []string
+	for _, h := range hints {
+		ret = append(ret, r.hostPort(h))
+	}
+	return ret
+}
+
+// Appends the configured port to a server given without one
+func (r *IterativeResolver) hostPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil || r.Port == "" {
+		return hostPort(server)
+	}
+	return net.JoinHostPort(server, r.Port)
+}
+
+// Returns the zone a response delegates name to below zone, its
+// nameservers and the TTL of the NS RRset. An answer, a negative answer or
+// an error yields no zone.
+func referral(resp *dns.Msg, zone, name string) (cut string, ns []string, ttl uint32) {
+	if len(resp.Answer) > 0 || resp.Rcode != dns.RcodeSuccess {
+		return "", nil, 0
+	}
+	for _, rr := range resp.Ns {
+		n, ok := rr.(*dns.NS)
+		if !ok || equalNames(n.Hdr.Name, zone) || !dns.IsSubDomain(dns.Fqdn(zone), n.Hdr.Name) || !dns.IsSubDomain(n.Hdr.Name, name) {
+			continue
+		}
+		if cut == "" {
+			cut, ttl = dns.Fqdn(strings.ToLower(n.Hdr.Name)), n.Hdr.Ttl
+		}
+		if equalNames(n.Hdr.Name, cut) {
+			ns = append(ns, dns.Fqdn(n.Ns))
+		}
+	}
+	return
+}
+
+// Returns the target of a CNAME RR owned by name if the answer has no RRs
+// of the queried type
+func cnameTarget(resp *dns.Msg, name string, qtype uint16) string {
+	if qtype == dns.TypeCNAME || qtype == dns.TypeANY || len(rrsetOf(*resp, name, qtype)) > 0 {
+		return ""
+	}
+	for _, rr := range resp.Answer {
+		if c, ok := rr.(*dns.CNAME); ok && equalNames(c.Hdr.Name, name) {
+			return dns.Fqdn(c.Target)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,84 @@
+package inspector
+
+import (
+	"context"
+
+	"github.com/miekg/dns"
+)
+
+// Resolves all IPv4 and IPv6 addresses of a nameserver
+func (in *Inspector) resolveAddresses(ctx context.Context, name string) []Address {
+	ret := []Address{}
+	for _, t := range []uint16{dns.TypeA, dns.TypeAAAA} {
+		m := in.dnssecQuery(ctx, name, t, in.resolver)
+		for _, r := range m.Answer {
+			switch a := r.(type) {
+			case *dns.A:
+				ret = append(ret, Address{IP: a.A.String()})
+			case *dns.AAAA:
+				ret = append(ret, Address{IP: a.AAAA.String(), IPv6: true})
+			}
+		}
+	}
+	return ret
+}
+
+// Tests every address of a nameserver and runs the zone checks against each
+// reachable one. The nameserver supports EDNS0 if all of its reachable
+// addresses do and is a resolver if any of them is.
+func (in *Inspector) checkAddresses(ctx context.Context, n *Nameserver, zone string) {
+	n.EDNS0 = false
+	n.Resolver = false
+	reachable := 0
+	edns0 := 0
+	for i := range n.Addresses {
+		a := &n.Addresses[i]
+		in.checkAddress(ctx, a, zone)
+		if a.Reachable {
+			reachable++
+			if in.isResolver(ctx, a, zone) {
+				n.Resolver = true
+			}
+		}
+		if a.EDNS0 {
+			edns0++
+		}
+	}
+	n.EDNS0 = reachable > 0 && edns0 == reachable
+}
+
+// Checks if a single address of an authoritative nameserver is reachable,
+// supports the EDNS0 extension (by checking the additional OPT-RR) and
+// returns DNSSEC material for the SOA RR of the zone. The zone checks of
+// checkZoneAt are run against reachable addresses. The SOA query is not
+// answered from the cache since the reachability has to be tested live.
+func (in *Inspector) checkAddress(ctx context.Context, a *Address, zone string) {
+	m := new(dns.Msg)
+	m.SetQuestion(dns.Fqdn(zone), dns.TypeSOA)
+	m.SetEdns0(4096, true)
+	r, err := in.exchange(ctx, in.server(a.IP), m)
+	a.Reachable = err == nil
+	a.EDNS0 = false
+	a.DNSSEC = false
+	a.SOASerial, a.KeyTags, a.NSEC3PARAM, a.CDS, a.CDNSKEY = 0, nil, "", nil, nil
+	a.Validation, a.ValidationError = false, ""
+	if err != nil {
+		a.Error = err.Error()
+		return
+	}
+	a.Error = ""
+	opt := r.IsEdns0()
+	a.EDNS0 = opt != nil
+	a.DNSSEC = opt != nil && opt.Do() && len(getRRsigs(*r, dns.TypeSOA)) > 0
+	in.checkZoneAt(ctx, a, zone, r)
+}
+
+// Returns the reachable addresses of a nameserver
+func (n *Nameserver) reachableAddresses() (ret []string) {
+	for _, a := range n.Addresses {
+		if a.Reachable {
+			ret = append(ret, a.IP)
+		}
+	}
+	return
+}
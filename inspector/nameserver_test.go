@@ -0,0 +1,100 @@
+package inspector
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestResolveAddresses(t *testing.T) {
+	in := New(Options{Resolver: newFakeResolver(t,
+		"ns1.example. 3600 IN A 192.0.2.1",
+		"ns1.example. 3600 IN AAAA 2001:db8::1",
+		"ns1.example. 3600 IN AAAA 2001:db8::2",
+	)})
+	addrs := in.resolveAddresses(context.Background(), "ns1.example.")
+	if len(addrs) != 3 {
+		t.Fatalf("Unexpected addresses %+v", addrs)
+	}
+	if addrs[0].IP != "192.0.2.1" || addrs[0].IPv6 || addrs[2].IP != "2001:db8::2" || !addrs[2].IPv6 {
+		t.Errorf("Unexpected addresses %+v", addrs)
+	}
+	n := Nameserver{Addresses: addrs}
+	n.Addresses[1].Reachable = true
+	if r := n.reachableAddresses(); len(r) != 1 || r[0] != "2001:db8::1" {
+		t.Errorf("Unexpected reachable addresses %v", r)
+	}
+}
+
+// Starts a local nameserver for the tests and returns its address
+func startTestServer(t *testing.T, handler dns.HandlerFunc) (string, *dns.Server) {
+	return startTestServerAt(t, "127.0.0.1:0", handler)
+}
+
+// Starts a local nameserver on addr for the tests and returns its address
+func startTestServerAt(t *testing.T, addr string, handler dns.HandlerFunc) (string, *dns.Server) {
+	pc, err := net.ListenPacket("udp", addr)
+	if err != nil {
+		t.Skipf("Cannot listen on udp: %s", err)
+	}
+	started := make(chan struct{})
+	s := &dns.Server{PacketConn: pc, Handler: handler, NotifyStartedFunc: func() { close(started) }}
+	go s.ActivateAndServe()
+	<-started
+	return pc.LocalAddr().String(), s
+}
+
+func TestIsResolver(t *testing.T) {
+	// Authoritative nameserver that also resolves and answers from cache
+	open, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.RecursionAvailable = true
+		q := r.Question[0]
+		if dns.IsSubDomain("example.", q.Name) {
+			m.Authoritative = true
+		} else {
+			rr, _ := dns.NewRR(q.Name + " 300 IN A 192.0.2.1")
+			m.Answer = append(m.Answer, rr)
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+	closed, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		if dns.IsSubDomain("example.", r.Question[0].Name) {
+			m.SetReply(r)
+			m.Authoritative = true
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+	// Closed server setting the RA bit in its refusals
+	refusing, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetRcode(r, dns.RcodeRefused)
+		m.RecursionAvailable = true
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+
+	in := New(Options{Resolver: newFakeResolver(t), ResolverProbes: []string{"probe.example", "probe.test"}})
+	a := &Address{IP: open}
+	if !in.isResolver(context.Background(), a, "example") {
+		t.Error("Open resolver not detected")
+	}
+	if !a.RecursionAvailable || !a.CacheSnooping || !a.RALeak {
+		t.Errorf("Unexpected result %+v", a)
+	}
+	a = &Address{IP: closed}
+	if in.isResolver(context.Background(), a, "example") || a.RALeak || a.CacheSnooping {
+		t.Errorf("Authoritative-only server reported as resolver: %+v", a)
+	}
+	a = &Address{IP: refusing}
+	if in.isResolver(context.Background(), a, "example") || a.RecursionAvailable || a.CacheSnooping {
+		t.Errorf("Server refusing with RA bit reported as resolver: %+v", a)
+	}
+}
@@ -0,0 +1,103 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// Common validating resolvers (BIND, Unbound, Knot Resolver, PowerDNS
+// Recursor) treat NSEC3 RRs with more iterations as insecure
+const nsec3InsecureIterations = 150
+
+// Checks the NSEC3PARAM RR and the NSEC3 RRs served by the authoritative
+// nameservers against the guidance of RFC 9276.
+func (in *Inspector) checkNSEC3Parameters(ctx context.Context, z *Zone) {
+	if z.Denial == nil {
+		return
+	}
+	var param *dns.NSEC3PARAM
+	m := in.dnssecQuery(ctx, z.FQDN, dns.TypeNSEC3PARAM, in.auth)
+	for _, rr := range m.Answer {
+		if p, ok := rr.(*dns.NSEC3PARAM); ok && equalNames(p.Hdr.Name, z.FQDN) {
+			param = p
+		}
+	}
+	m = in.dnssecQuery(ctx, z.nxdomainProbe(), dns.TypeA, in.auth)
+	_, nsec3 := splitDenialRRs(m.Ns)
+	if param == nil && len(nsec3) == 0 {
+		return
+	}
+	z.NSEC3Parameters = evaluateNSEC3(param, nsec3)
+}
+
+// Evaluates the NSEC3 parameters (RFC 9276 section 3.1: no additional
+// iterations, no salt, no opt-out) and checks that the NSEC3PARAM RR matches
+// the served NSEC3 RRs. The served NSEC3 RRs are rated as validators see
+// them; the NSEC3PARAM RR if the zone serves none.
+func evaluateNSEC3(param *dns.NSEC3PARAM, nsec3 []*dns.NSEC3) *NSEC3Parameters {
+	p := &NSEC3Parameters{}
+	var hash uint8
+	var iterations uint16
+	var salt string
+	if param != nil {
+		p.Param = fmt.Sprintf("%d %d %d %s", param.Hash, param.Flags, param.Iterations, saltString(param.Salt))
+		hash, iterations, salt = param.Hash, param.Iterations, param.Salt
+		if param.Flags != 0 {
+			p.Issues = append(p.Issues, "the flags of the NSEC3PARAM RR have to be 0 (RFC 5155 section 4.1.2)")
+		}
+	} else {
+		p.Issues = append(p.Issues, "no NSEC3PARAM RR at the apex")
+	}
+	if len(nsec3) > 0 {
+		n := nsec3[0]
+		for _, x := range nsec3 {
+			if x.Flags&1 != 0 {
+				p.OptOut = true
+			}
+		}
+		p.Served = fmt.Sprintf("%d %d %d %s", n.Hash, n.Flags&^1, n.Iterations, saltString(n.Salt))
+		if param != nil && (n.Hash != param.Hash || n.Iterations != param.Iterations || !equalSalt(n.Salt, param.Salt)) {
+			p.Issues = append(p.Issues, fmt.Sprintf("NSEC3PARAM (%s) does not match the served NSEC3 RRs (%s)", p.Param, p.Served))
+		}
+		hash, iterations, salt = n.Hash, n.Iterations, n.Salt
+	} else {
+		p.Issues = append(p.Issues, "NSEC3PARAM RR is published, but no NSEC3 RRs are served")
+	}
+
+	p.Iterations = int(iterations)
+	p.SaltLength = len(salt) / 2
+	if salt == "-" {
+		p.SaltLength = 0
+	}
+	if hash != dns.SHA1 {
+		p.Issues = append(p.Issues, fmt.Sprintf("unknown NSEC3 hash algorithm %d", hash))
+	}
+	if iterations > nsec3InsecureIterations {
+		p.LikelyInsecure = true
+		p.Issues = append(p.Issues, fmt.Sprintf("%d additional iterations: common validating resolvers treat the zone as insecure, some answer SERVFAIL", iterations))
+	} else if iterations > 0 {
+		p.Issues = append(p.Issues, fmt.Sprintf("%d additional iterations: RFC 9276 requires 0, validating resolvers may treat the zone as insecure or answer SERVFAIL", iterations))
+	}
+	if p.SaltLength > 0 {
+		p.Issues = append(p.Issues, "NSEC3 salt is not empty (RFC 9276 section 3.1)")
+	}
+	if p.OptOut {
+		p.Issues = append(p.Issues, "opt-out is used; RFC 9276 discourages it unless the zone has very many insecure delegations")
+	}
+	p.Compliant = hash == dns.SHA1 && iterations == 0 && p.SaltLength == 0 && !p.OptOut
+	return p
+}
+
+func saltString(salt string) string {
+	if salt == "" {
+		return "-"
+	}
+	return salt
+}
+
+func equalSalt(a, b string) bool {
+	return strings.EqualFold(saltString(a), saltString(b))
+}
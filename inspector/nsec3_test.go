@@ -0,0 +1,44 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestEvaluateNSEC3(t *testing.T) {
+	nsec3 := func(records ...string) (ret []*dns.NSEC3) {
+		for _, rr := range mustRRs(t, records...) {
+			ret = append(ret, rr.(*dns.NSEC3))
+		}
+		return
+	}
+	param := func(s string) *dns.NSEC3PARAM {
+		return mustRRs(t, "example. 0 IN NSEC3PARAM "+s)[0].(*dns.NSEC3PARAM)
+	}
+	const owner = "2vptu5timamqttgl4luu9kg21e0aor3s.example. 3600 IN NSEC3 "
+	const next = " 2vptu5timamqttgl4luu9kg21e0aor3t A"
+
+	tests := []struct {
+		name      string
+		param     *dns.NSEC3PARAM
+		nsec3     []*dns.NSEC3
+		compliant bool
+		insecure  bool
+		issues    int
+	}{
+		{"rfc 9276", param("1 0 0 -"), nsec3(owner + "1 0 0 -" + next), true, false, 0},
+		{"salt and iterations", param("1 0 10 aabbccdd"), nsec3(owner + "1 0 10 aabbccdd" + next), false, false, 2},
+		{"high iterations", param("1 0 200 -"), nsec3(owner + "1 0 200 -" + next), false, true, 1},
+		{"opt-out", param("1 0 0 -"), nsec3(owner + "1 1 0 -" + next), false, false, 1},
+		{"mismatch", param("1 0 0 -"), nsec3(owner + "1 0 5 -" + next), false, false, 2},
+		{"no NSEC3PARAM", nil, nsec3(owner + "1 0 0 -" + next), true, false, 1},
+		{"no NSEC3", param("1 0 0 -"), nil, true, false, 1},
+	}
+	for _, tt := range tests {
+		p := evaluateNSEC3(tt.param, tt.nsec3)
+		if p.Compliant != tt.compliant || p.LikelyInsecure != tt.insecure || len(p.Issues) != tt.issues {
+			t.Errorf("%s: got %+v", tt.name, p)
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package inspector
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// DefaultPolicy is the name of the built-in policy used if none is configured
+const DefaultPolicy = "bsi"
+
+// Policy describes the crypto compliance rules keys are evaluated against.
+// Policies are loaded from JSON files so the rules can be updated without a
+// code release.
+type Policy struct {
+	Name       string          `json:"name"`
+	Version    string          `json:"version"`
+	Algorithms []AlgorithmRule `json:"algorithms"`
+	Hashes     []HashRule      `json:"hashes"`
+}
+
+// AlgorithmRule describes a DNSKEY algorithm (RFC 8624 numbering)
+type AlgorithmRule struct {
+	Number uint8  `json:"number"`
+	Alg    string `json:"alg"`
+	Hash   string `json:"hash"`
+	// KeyLength of algorithms with a fixed key size (e.g. ECDSA). RSA and
+	// DSA key lengths are read from the key material.
+	KeyLength int           `json:"keyLength,omitempty"`
+	KeySizes  []KeySizeRule `json:"keySizes"`
+}
+
+// KeySizeRule is the verdict for keys of at least MinLength bits
+type KeySizeRule struct {
+	MinLength int    `json:"minLength"`
+	Comment   string `json:"comment"`
+	Until     string `json:"until"`
+}
+
+// HashRule is the verdict for a hash function
+type HashRule struct {
+	Name    string `json:"name"`
+	Comment string `json:"comment"`
+	Until   string `json:"until"`
+}
+
+// Built-in policies by name
+var builtinPolicies = map[string]string{
+	"bsi":     bsiPolicy,
+	"rfc8624": rfc8624Policy,
+	"nist":    nistPolicy,
+}
+
+// BuiltinPolicies returns the names of the built-in policies
+func BuiltinPolicies() []string {
+	var names []string
+	for n := range builtinPolicies {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadPolicies loads a list of built-in policy names or policy files
+func LoadPolicies(namesOrPaths []string) ([]*Policy, error) {
+	var ret []*Policy
+	for _, n := range namesOrPaths {
+		p, err := LoadPolicy(n)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %s", n, err)
+		}
+		ret = append(ret, p)
+	}
+	return ret, nil
+}
+
+// LoadPolicy returns the built-in policy with the given name or reads the
+// policy from the JSON file at the given path.
+func LoadPolicy(nameOrPath string) (*Policy, error) {
+	data := []byte(builtinPolicies[nameOrPath])
+	if len(data) == 0 {
+		var err error
+		if data, err = ioutil.ReadFile(nameOrPath); err != nil {
+			return nil, err
+		}
+	}
+	return parsePolicy(data)
+}
+
+func parsePolicy(data []byte) (*Policy, error) {
+	p := &Policy{}
+	if err := json.Unmarshal(data, p); err != nil {
+		return nil, fmt.Errorf("cannot parse policy: %s", err)
+	}
+	if p.Name == "" || len(p.Algorithms) == 0 {
+		return nil, errors.New("policy needs a name and at least one algorithm")
+	}
+	for i := range p.Algorithms {
+		a := &p.Algorithms[i]
+		if len(a.KeySizes) == 0 {
+			return nil, fmt.Errorf("policy %s: algorithm %d has no key sizes", p.Name, a.Number)
+		}
+		// Longest key size first, so the first matching rule wins
+		sort.Slice(a.KeySizes, func(i, j int) bool { return a.KeySizes[i].MinLength > a.KeySizes[j].MinLength })
+	}
+	return p, nil
+}
+
+// String returns name and version of the policy
+func (p *Policy) String() string {
+	return fmt.Sprintf("%s (%s)", p.Name, p.Version)
+}
+
+// Returns the rule for a DNSKEY algorithm number or nil
+func (p *Policy) algorithm(number uint8) *AlgorithmRule {
+	for i := range p.Algorithms {
+		if p.Algorithms[i].Number == number {
+			return &p.Algorithms[i]
+		}
+	}
+	return nil
+}
+
+// Returns the verdict for a hash function in year
+func (p *Policy) hash(name string, year int) (comment string, until string) {
+	for _, h := range p.Hashes {
+		if h.Name == name {
+			return verdictAt(h.Comment, h.Until, year), h.Until
+		}
+	}
+	return "UNKNOWN", ""
+}
+
+// Returns the verdict for a key of the given length in year
+func (a *AlgorithmRule) keySize(length int, year int) (comment string, until string) {
+	for _, s := range a.KeySizes {
+		if length >= s.MinLength {
+			return verdictAt(s.Comment, s.Until, year), s.Until
+		}
+	}
+	return "NON-COMPLIANT", "0000"
+}
+
+// A rule is NON-COMPLIANT after its until year. "0000" marks rules that were
+// never compliant, their comment is kept.
+func verdictAt(comment, until string, year int) string {
+	if y, err := strconv.Atoi(until); err == nil && y > 0 && y < year {
+		return "NON-COMPLIANT"
+	}
+	return comment
+}
+
+// Recommendations of BSI TR-02102-1 version 2025-01. RSA and DSA keys need
+// 3000 bits, the 2000 bit RSA keys of the transition period were compliant
+// until the end of 2023 and DSA is only recommended until 2029.
+const bsiPolicy = `{
+	"name": "BSI TR-02102-1",
+	"version": "2025-01",
+	"hashes": [
+		{"name": "MD5", "comment": "NON-COMPLIANT", "until": "2004"},
+		{"name": "SHA-1", "comment": "NON-COMPLIANT", "until": "2015"},
+		{"name": "SHA-256", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHA-384", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHA-512", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHAKE-256", "comment": "COMPLIANT", "until": "9999"}
+	],
+	"algorithms": [
+		{"number": 1, "alg": "RSA", "hash": "MD5", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2000, "comment": "COMPLIANT", "until": "2023"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 3, "alg": "DSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "2029"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 5, "alg": "RSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2000, "comment": "COMPLIANT", "until": "2023"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 6, "alg": "DSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "2029"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 7, "alg": "RSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2000, "comment": "COMPLIANT", "until": "2023"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 8, "alg": "RSA", "hash": "SHA-256", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2000, "comment": "COMPLIANT", "until": "2023"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 10, "alg": "RSA", "hash": "SHA-512", "keySizes": [
+			{"minLength": 3000, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2000, "comment": "COMPLIANT", "until": "2023"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 13, "alg": "ECDSA P-256", "hash": "SHA-256", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 14, "alg": "ECDSA P-384", "hash": "SHA-384", "keyLength": 384, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 15, "alg": "Ed25519", "hash": "SHA-512", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 16, "alg": "Ed448", "hash": "SHAKE-256", "keyLength": 456, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]}
+	]
+}`
+
+// Algorithm requirements for signing of RFC 8624 section 3.1. RFC 8624 rates
+// an algorithm as a whole, so the hash of the algorithm is not rated on its
+// own. The hash verdicts are the DS digest requirements of section 3.3.
+const rfc8624Policy = `{
+	"name": "RFC 8624",
+	"version": "2019-06",
+	"hashes": [
+		{"name": "SHA-1", "comment": "MUST NOT", "until": "0000"},
+		{"name": "SHA-256", "comment": "MUST", "until": "9999"},
+		{"name": "GOST R 34.11-94", "comment": "MUST NOT", "until": "0000"},
+		{"name": "SHA-384", "comment": "MAY", "until": "9999"}
+	],
+	"algorithms": [
+		{"number": 1, "alg": "RSA", "keySizes": [
+			{"minLength": 0, "comment": "MUST NOT", "until": "0000"}]},
+		{"number": 3, "alg": "DSA", "keySizes": [
+			{"minLength": 0, "comment": "MUST NOT", "until": "0000"}]},
+		{"number": 5, "alg": "RSA", "keySizes": [
+			{"minLength": 0, "comment": "NOT RECOMMENDED", "until": "9999"}]},
+		{"number": 6, "alg": "DSA", "keySizes": [
+			{"minLength": 0, "comment": "MUST NOT", "until": "0000"}]},
+		{"number": 7, "alg": "RSA", "keySizes": [
+			{"minLength": 0, "comment": "NOT RECOMMENDED", "until": "9999"}]},
+		{"number": 8, "alg": "RSA", "keySizes": [
+			{"minLength": 0, "comment": "MUST", "until": "9999"}]},
+		{"number": 10, "alg": "RSA", "keySizes": [
+			{"minLength": 0, "comment": "NOT RECOMMENDED", "until": "9999"}]},
+		{"number": 12, "alg": "ECC-GOST", "keyLength": 512, "keySizes": [
+			{"minLength": 0, "comment": "MUST NOT", "until": "0000"}]},
+		{"number": 13, "alg": "ECDSA P-256", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "MUST", "until": "9999"}]},
+		{"number": 14, "alg": "ECDSA P-384", "keyLength": 384, "keySizes": [
+			{"minLength": 0, "comment": "MAY", "until": "9999"}]},
+		{"number": 15, "alg": "Ed25519", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "RECOMMENDED", "until": "9999"}]},
+		{"number": 16, "alg": "Ed448", "keyLength": 456, "keySizes": [
+			{"minLength": 0, "comment": "MAY", "until": "9999"}]}
+	]
+}`
+
+// Key lengths of NIST SP 800-57 Part 1 Rev. 5 table 4: 112 bit security
+// strength (RSA/DSA 2048) is acceptable through 2030, 128 bit and more
+// beyond. SHA-1 is disallowed for signature generation (SP 800-131A).
+const nistPolicy = `{
+	"name": "NIST SP 800-57 Part 1",
+	"version": "Rev. 5 (2020-05)",
+	"hashes": [
+		{"name": "MD5", "comment": "NON-COMPLIANT", "until": "0000"},
+		{"name": "SHA-1", "comment": "NON-COMPLIANT", "until": "2013"},
+		{"name": "GOST R 34.11-94", "comment": "NON-COMPLIANT", "until": "0000"},
+		{"name": "SHA-256", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHA-384", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHA-512", "comment": "COMPLIANT", "until": "9999"},
+		{"name": "SHAKE-256", "comment": "COMPLIANT", "until": "9999"}
+	],
+	"algorithms": [
+		{"number": 1, "alg": "RSA", "hash": "MD5", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 3, "alg": "DSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 5, "alg": "RSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 6, "alg": "DSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 7, "alg": "RSA", "hash": "SHA-1", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 8, "alg": "RSA", "hash": "SHA-256", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 10, "alg": "RSA", "hash": "SHA-512", "keySizes": [
+			{"minLength": 3072, "comment": "COMPLIANT", "until": "9999"},
+			{"minLength": 2048, "comment": "COMPLIANT", "until": "2030"},
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 12, "alg": "ECC-GOST", "hash": "GOST R 34.11-94", "keyLength": 512, "keySizes": [
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"}]},
+		{"number": 13, "alg": "ECDSA P-256", "hash": "SHA-256", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 14, "alg": "ECDSA P-384", "hash": "SHA-384", "keyLength": 384, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 15, "alg": "Ed25519", "hash": "SHA-512", "keyLength": 256, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]},
+		{"number": 16, "alg": "Ed448", "hash": "SHAKE-256", "keyLength": 456, "keySizes": [
+			{"minLength": 0, "comment": "COMPLIANT", "until": "9999"}]}
+	]
+}`
@@ -0,0 +1,191 @@
+package inspector
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func newTestKey(t *testing.T, alg uint8, bits int) dns.DNSKEY {
+	key := dns.DNSKEY{
+		Hdr:       dns.RR_Header{Name: "example.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET, Ttl: 3600},
+		Flags:     KSK,
+		Protocol:  3,
+		Algorithm: alg,
+	}
+	if _, err := key.Generate(bits); err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func TestCheckKeyDefaultPolicy(t *testing.T) {
+	p, err := LoadPolicy(DefaultPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		alg       uint8
+		bits      int
+		keyLength int
+		aComment  string
+		aUntil    string
+		hash      string
+		hComment  string
+	}{
+		{dns.RSASHA1, 1024, 1024, "NON-COMPLIANT", "0000", "SHA-1", "NON-COMPLIANT"},
+		{dns.RSASHA256, 2048, 2048, "COMPLIANT", "2023", "SHA-256", "COMPLIANT"},
+		{dns.RSASHA512, 3072, 3072, "COMPLIANT", "9999", "SHA-512", "COMPLIANT"},
+		{dns.ECDSAP256SHA256, 256, 256, "COMPLIANT", "9999", "SHA-256", "COMPLIANT"},
+		{dns.ED25519, 256, 256, "COMPLIANT", "9999", "SHA-512", "COMPLIANT"},
+	}
+	for _, tt := range tests {
+		k := Key{}
+		checkKey(newTestKey(t, tt.alg, tt.bits), &k, []*Policy{p}, 2020)
+		if k.Type != "KSK" || k.KeyLength != tt.keyLength || k.AComment != tt.aComment || k.AUntil != tt.aUntil ||
+			k.Hash != tt.hash || k.HComment != tt.hComment || k.Policy != p.String() {
+			t.Errorf("%s/%d: got %+v", dns.AlgorithmToString[tt.alg], tt.bits, k)
+		}
+	}
+}
+
+func TestRevokedKey(t *testing.T) {
+	key := newTestKey(t, dns.ECDSAP256SHA256, 256)
+	key.Flags |= dns.REVOKE
+	keys := getDNSKEYs(dns.Msg{Answer: []dns.RR{&key}}, KSK)
+	if len(keys) != 1 {
+		t.Fatalf("Revoked KSK not selected")
+	}
+	k := Key{}
+	checkKey(keys[0], &k, []*Policy{}, 2020)
+	if k.Type != "KSK" || !k.Revoked {
+		t.Errorf("Unexpected key %+v", k)
+	}
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"name": "strict", "version": "1", "hashes": [{"name": "SHA-256", "comment": "COMPLIANT", "until": "2030"}],
+		"algorithms": [{"number": 8, "alg": "RSA", "hash": "SHA-256", "keySizes": [
+			{"minLength": 0, "comment": "NON-COMPLIANT", "until": "0000"},
+			{"minLength": 4096, "comment": "COMPLIANT", "until": "2030"}]}]}`)
+	f.Close()
+
+	p, err := LoadPolicy(f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.String() != "strict (1)" {
+		t.Errorf("got policy %s", p)
+	}
+	k := Key{}
+	checkKey(newTestKey(t, dns.RSASHA256, 2048), &k, []*Policy{p}, 2020)
+	if k.AComment != "NON-COMPLIANT" || k.HUntil != "2030" {
+		t.Errorf("RSA 2048: got %+v", k)
+	}
+	k = Key{}
+	checkKey(newTestKey(t, dns.ECDSAP256SHA256, 256), &k, []*Policy{p}, 2020)
+	if k.Alg != "" || k.AComment != "" {
+		t.Errorf("algorithm missing in the policy was rated: %+v", k)
+	}
+
+	for _, data := range []string{
+		`{"name": "empty"}`,
+		`{"name": "no key sizes", "algorithms": [{"number": 8}]}`,
+		`not json`,
+	} {
+		if _, err := parsePolicy([]byte(data)); err == nil {
+			t.Errorf("invalid policy %s accepted", data)
+		}
+	}
+}
+
+func TestCheckKeyPolicies(t *testing.T) {
+	policies, err := LoadPolicies(BuiltinPolicies())
+	if err != nil {
+		t.Fatal(err)
+	}
+	k := Key{}
+	checkKey(newTestKey(t, dns.RSASHA1, 2048), &k, policies, 2020)
+	want := map[string]string{
+		"BSI TR-02102-1 (2025-01)":                 "COMPLIANT 2023 NON-COMPLIANT",
+		"RFC 8624 (2019-06)":                       "NOT RECOMMENDED 9999 ",
+		"NIST SP 800-57 Part 1 (Rev. 5 (2020-05))": "COMPLIANT 2030 NON-COMPLIANT",
+	}
+	if len(k.Compliance) != len(want) {
+		t.Fatalf("got %d verdicts, want %d", len(k.Compliance), len(want))
+	}
+	for _, c := range k.Compliance {
+		if got := c.AComment + " " + c.AUntil + " " + c.HComment; got != want[c.Policy] {
+			t.Errorf("%s: got %q, want %q", c.Policy, got, want[c.Policy])
+		}
+	}
+	// The first policy fills the verdict fields of the key
+	if k.Policy != k.Compliance[0].Policy || k.AComment != k.Compliance[0].AComment || k.Hash != "SHA-1" {
+		t.Errorf("got %+v", k)
+	}
+}
+
+func TestCheckKeyEvaluationYear(t *testing.T) {
+	p, err := LoadPolicy(DefaultPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := newTestKey(t, dns.RSASHA256, 2048)
+	for year, want := range map[int]string{2023: "COMPLIANT", 2024: "NON-COMPLIANT"} {
+		k := Key{}
+		checkKey(key, &k, []*Policy{p}, year)
+		if k.AComment != want || k.AUntil != "2023" {
+			t.Errorf("%d: got %s until %s, want %s", year, k.AComment, k.AUntil, want)
+		}
+	}
+}
+
+func TestCheckKeyDefaultPolicyCurrentYear(t *testing.T) {
+	p, err := LoadPolicy(DefaultPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Keys recommended today must not expire with the default policy
+	for _, key := range []dns.DNSKEY{
+		newTestKey(t, dns.ECDSAP256SHA256, 256),
+		newTestKey(t, dns.ECDSAP384SHA384, 384),
+		newTestKey(t, dns.RSASHA256, 3072),
+	} {
+		k := Key{}
+		checkKey(key, &k, []*Policy{p}, time.Now().Year())
+		if k.AComment != "COMPLIANT" || k.HComment != "COMPLIANT" {
+			t.Errorf("%s: got %s/%s", dns.AlgorithmToString[key.Algorithm], k.AComment, k.HComment)
+		}
+	}
+}
+
+func TestCheckKeyMalformed(t *testing.T) {
+	p, err := LoadPolicy(DefaultPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", "AQ==", "AAAB", "not base64"} {
+		var k Key
+		rr := newTestKey(t, dns.RSASHA256, 2048)
+		rr.PublicKey = key
+		checkKey(rr, &k, []*Policy{p}, 2020)
+		if k.Error == "" {
+			t.Errorf("%q: no error", key)
+		}
+	}
+	var k Key
+	rr := dns.DNSKEY{Hdr: dns.RR_Header{Name: "example.", Rrtype: dns.TypeDNSKEY, Class: dns.ClassINET},
+		Flags: KSK, Protocol: 3, Algorithm: dns.DSA, PublicKey: "CA=="}
+	checkKey(rr, &k, []*Policy{p}, 2020)
+	if k.Error == "" {
+		t.Errorf("Truncated DSA key: no error")
+	}
+}
@@ -0,0 +1,184 @@
+package inspector
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/miekg/dns"
+)
+
+// errReplay is returned instead of sending a query while replaying
+var errReplay = errors.New("query is not in the cache, nothing is sent while replaying")
+
+// failureSuffix marks cache files recording a failed exchange. They are only
+// used when replaying, a failure is retried otherwise.
+const failureSuffix = ".failed"
+
+// replayMisses collects the queries of a replayed audit that are missing in
+// the cache. It is safe for concurrent use; a nil replayMisses is an audit
+// that is not replayed.
+type replayMisses struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+type replayKey struct{}
+
+// Returns a context marking the audit as replayed, queries missing in the
+// cache are collected in m
+func withReplay(ctx context.Context, m *replayMisses) context.Context {
+	return context.WithValue(ctx, replayKey{}, m)
+}
+
+// Returns the replayMisses of the audit of ctx, nil if it is not replayed
+func replaying(ctx context.Context) *replayMisses {
+	m, _ := ctx.Value(replayKey{}).(*replayMisses)
+	return m
+}
+
+// Records a query missing in the cache
+func (m *replayMisses) add(query string) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queries = append(m.queries, query)
+}
+
+// Returns an error if queries were missing in the cache
+func (m *replayMisses) err() error {
+	if m == nil {
+		return nil
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if len(m.queries) == 0 {
+		return nil
+	}
+	return fmt.Errorf("replay incomplete: %d queries are not in the cache, e.g. %s", len(m.queries), m.queries[0])
+}
+
+// Describes a query sent to r for replayMisses
+func queryString(name string, rrType uint16, r Resolver) string {
+	return fmt.Sprintf("%s %s to %s", dns.Fqdn(name), dns.TypeToString[rrType], resolverID(r))
+}
+
+// Sends m to r for the checks testing the servers live, e.g. their
+// reachability. Responses are not reused from the cache, but recorded in it
+// along with failures, so that the audit can be replayed.
+func (in *Inspector) exchange(ctx context.Context, r Resolver, m *dns.Msg) (*dns.Msg, error) {
+	if in.opts.Cache == "" {
+		return r.Exchange(ctx, m)
+	}
+	file := in.exchangeFile(r, m)
+	if in.opts.Replay {
+		if data, err := ioutil.ReadFile(file); err == nil {
+			resp := new(dns.Msg)
+			if err := resp.Unpack(data); err != nil {
+				return nil, err
+			}
+			return resp, nil
+		}
+		if data, err := ioutil.ReadFile(file + failureSuffix); err == nil {
+			return nil, errors.New(string(data))
+		}
+		replaying(ctx).add(queryString(m.Question[0].Name, m.Question[0].Qtype, r))
+		return nil, errReplay
+	}
+	resp, err := r.Exchange(ctx, m)
+	in.record(file, resp, err)
+	return resp, err
+}
+
+// Returns the path of the cache file for a live exchange. Queries for the
+// same name and type differ in their flags, so they are part of the hash.
+func (in *Inspector) exchangeFile(r Resolver, m *dns.Msg) string {
+	id := fmt.Sprint(resolverID(r), m.RecursionDesired, m.CheckingDisabled)
+	if opt := m.IsEdns0(); opt != nil {
+		id += fmt.Sprint(opt.UDPSize(), opt.Do())
+	}
+	sum := sha256.Sum256([]byte(id))
+	q := m.Question[0]
+	return filepath.Join(in.opts.Cache, fmt.Sprintf("live_%s_%d_%x", q.Name, q.Qtype, sum[:8]))
+}
+
+// Writes the response or the failure of an exchange to the cache file
+func (in *Inspector) record(file string, resp *dns.Msg, err error) {
+	if err != nil || resp == nil {
+		if err == nil {
+			err = errors.New("no response")
+		}
+		if e := ioutil.WriteFile(file+failureSuffix, []byte(err.Error()), 0777); e != nil {
+			Warning.Printf("Cannot write cache file: %s\n", e)
+		}
+		return
+	}
+	os.Remove(file + failureSuffix)
+	data, err := resp.Pack()
+	if err == nil {
+		err = ioutil.WriteFile(file, data, 0777)
+	}
+	if err != nil {
+		Warning.Printf("Cannot write cache file: %s\n", err)
+	}
+}
+
+// Returns the name below zone whose NXDOMAIN response is tested. A random
+// name is chosen per zone and kept in the cache, so a replay asks for the
+// same name as the recorded audit.
+func (in *Inspector) probeName(ctx context.Context, zone string) string {
+	if in.opts.Cache == "" {
+		return randomName(zone)
+	}
+	file := filepath.Join(in.opts.Cache, fmt.Sprintf("probe_%s", zone))
+	if info, err := os.Stat(file); err == nil && in.cacheValid(info) {
+		if data, err := ioutil.ReadFile(file); err == nil {
+			return string(data)
+		}
+	}
+	name := randomName(zone)
+	if in.opts.Replay {
+		replaying(ctx).add("NXDOMAIN probe name of " + dns.Fqdn(zone))
+		return name
+	}
+	if err := ioutil.WriteFile(file, []byte(name), 0777); err != nil {
+		Warning.Printf("Cannot write cache file: %s\n", err)
+	}
+	return name
+}
+
+// Traces the referrals from the root to fqdn. The trace is kept in the cache
+// and reused when replaying.
+func (in *Inspector) trace(ctx context.Context, r *IterativeResolver, fqdn string) ([]Referral, error) {
+	if in.opts.Cache == "" {
+		return r.Trace(ctx, fqdn, dns.TypeDNSKEY)
+	}
+	file := filepath.Join(in.opts.Cache, fmt.Sprintf("trace_%s", fqdn))
+	var refs []Referral
+	if in.opts.Replay {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			replaying(ctx).add("referrals to " + dns.Fqdn(fqdn))
+			return nil, errReplay
+		}
+		err = json.Unmarshal(data, &refs)
+		return refs, err
+	}
+	refs, err := r.Trace(ctx, fqdn, dns.TypeDNSKEY)
+	if err != nil {
+		return refs, err
+	}
+	data, _ := json.Marshal(refs)
+	if err := ioutil.WriteFile(file, data, 0777); err != nil {
+		Warning.Printf("Cannot write cache file: %s\n", err)
+	}
+	return refs, nil
+}
@@ -0,0 +1,77 @@
+package inspector
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+// offlineResolver fails the test if a query is sent
+type offlineResolver struct{ t *testing.T }
+
+func (o offlineResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	o.t.Errorf("Query for %s sent while replaying", m.Question[0].Name)
+	return nil, errReplay
+}
+
+func TestReplay(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnssec-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	query := func(name string) *dns.Msg {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeSOA)
+		m.SetEdns0(4096, true)
+		return m
+	}
+
+	// Record a live exchange, a failed one and the probe name of a zone
+	ctx := context.Background()
+	in := New(Options{Resolver: newFakeResolver(t, "example. 3600 IN SOA ns1.example. hostmaster.example. 1 7200 3600 1209600 3600"), Cache: dir})
+	if _, err := in.exchange(ctx, in.resolver, query("example.")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := in.exchange(ctx, failingResolver{}, query("example.")); err == nil {
+		t.Fatal("Failing resolver answered")
+	}
+	probe := in.probeName(ctx, "example")
+
+	in = New(Options{Resolver: offlineResolver{t}, Cache: dir, Replay: true})
+	misses := &replayMisses{}
+	ctx = withReplay(ctx, misses)
+	if r, err := in.exchange(ctx, newFakeResolver(t), query("example.")); err != nil || len(r.Answer) != 1 {
+		t.Errorf("Recorded response not replayed: %v %v", r, err)
+	}
+	if _, err := in.exchange(ctx, failingResolver{}, query("example.")); err == nil || err.Error() != "unreachable" {
+		t.Errorf("Recorded failure not replayed: %v", err)
+	}
+	if p := in.probeName(ctx, "example"); p != probe {
+		t.Errorf("Probe name %s, want %s", p, probe)
+	}
+	if err := misses.err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Queries missing in the cache fail the replay without being sent
+	if _, err := in.exchange(ctx, in.resolver, query("www.example.")); err != errReplay {
+		t.Errorf("Unexpected error %v", err)
+	}
+	if m := in.dnssecQuery(ctx, "example", dns.TypeDNSKEY, in.resolver); m.Rcode != dns.RcodeServerFailure {
+		t.Errorf("Unexpected response %v", m)
+	}
+	if _, err := (&ServerResolver{Servers: []string{"192.0.2.1:53"}}).Exchange(ctx, query("example.")); err != errReplay {
+		t.Errorf("ServerResolver sent a query while replaying: %v", err)
+	}
+	if len(misses.queries) != 3 || misses.err() == nil {
+		t.Errorf("Unexpected misses %q", misses.queries)
+	}
+
+	if _, err := New(Options{Resolver: offlineResolver{t}, Replay: true}).Inspect(context.Background(), "example"); err == nil {
+		t.Error("Replay without cache directory succeeded")
+	}
+}
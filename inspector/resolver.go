@@ -0,0 +1,200 @@
+package inspector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Resolver sends a DNS query and returns the response. Every lookup of the
+// inspector goes through a Resolver, so the transport can be replaced
+// without touching the validation code.
+type Resolver interface {
+	Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error)
+}
+
+// ServerResolver sends queries to a fixed list of servers. The servers are
+// tried in order until one of them answers, each with the configured
+// retries.
+type ServerResolver struct {
+	// Servers as host or host:port, queried over UDP on port 53 if no port
+	// is given, or as URL selecting the transport, e.g. tcp://9.9.9.9,
+	// tls://9.9.9.9#name=dns.quad9.net or https://dns.quad9.net/dns-query
+	// (see parseServer).
+	Servers []string
+	// Timeout bounds a single exchange. Zero uses the dns package default.
+	Timeout time.Duration
+	RetryPolicy
+
+	// parsed servers by address, so DNS over HTTPS connections are reused
+	mu     sync.Mutex
+	parsed map[string]*server
+}
+
+// RetryPolicy configures how often a failed exchange is repeated
+type RetryPolicy struct {
+	// Retries is the number of further attempts per server after a failed
+	// exchange
+	Retries int
+	// Backoff is the delay before the first retry, doubled for each
+	// further retry
+	Backoff time.Duration
+	// Health tracks the failures per server if set. Servers failing
+	// repeatedly are skipped. If nil the failures are tracked per audit.
+	Health *ServerHealth
+}
+
+// NewSystemResolver returns a ServerResolver for the nameservers configured
+// in /etc/resolv.conf.
+func NewSystemResolver() (*ServerResolver, error) {
+	config, err := dns.ClientConfigFromFile("/etc/resolv.conf")
+	if err != nil {
+		return nil, err
+	}
+	s := &ServerResolver{}
+	for _, x := range config.Servers {
+		s.Servers = append(s.Servers, net.JoinHostPort(x, config.Port))
+	}
+	return s, nil
+}
+
+// Exchange implements Resolver
+func (s *ServerResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	if r := replaying(ctx); r != nil {
+		// Queries not answered from the cache fail the replayed audit
+		r.add(queryString(m.Question[0].Name, m.Question[0].Qtype, s))
+		return nil, errReplay
+	}
+	err := errors.New("no server to send the query to")
+	health := s.health(ctx)
+	for _, x := range s.Servers {
+		if health.skip(x) {
+			err = fmt.Errorf("server %s is unreachable", x)
+			continue
+		}
+		var srv *server
+		if srv, err = s.server(x); err != nil {
+			continue
+		}
+		for i := 0; i <= s.Retries; i++ {
+			if i > 0 && !backoff(ctx, s.Backoff, i) {
+				return nil, ctx.Err()
+			}
+			var r *dns.Msg
+			if r, err = srv.exchange(ctx, m, s.Timeout); r != nil {
+				health.record(x, nil)
+				return r, nil
+			}
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+		}
+		health.record(x, err)
+	}
+	return nil, err
+}
+
+// Returns the parsed server address x
+func (s *ServerResolver) server(x string) (*server, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if srv, ok := s.parsed[x]; ok {
+		return srv, nil
+	}
+	srv, err := parseServer(x)
+	if err != nil {
+		return nil, err
+	}
+	if s.parsed == nil {
+		s.parsed = map[string]*server{}
+	}
+	s.parsed[x] = srv
+	return srv, nil
+}
+
+func (s *ServerResolver) String() string {
+	return strings.Join(s.Servers, ",")
+}
+
+// AuthoritativeResolver sends queries only to the authoritative nameservers
+// of the zone the queried name belongs to. DS queries go to the
+// nameservers of the parent zone, which is authoritative for DS RRs.
+type AuthoritativeResolver struct {
+	// Lookup is used to find the NS RRset of the zone
+	Lookup Resolver
+	// Timeout bounds a single exchange. Zero uses the dns package default.
+	Timeout time.Duration
+	RetryPolicy
+}
+
+// Exchange implements Resolver
+func (a *AuthoritativeResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	if len(m.Question) == 0 {
+		return nil, errors.New("query without question")
+	}
+	name := dns.Fqdn(m.Question[0].Name)
+	if m.Question[0].Qtype == dns.TypeDS {
+		name = parentName(name)
+	}
+	servers, err := a.nameservers(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	q := m.Copy()
+	q.RecursionDesired = false
+	s := &ServerResolver{Servers: servers, Timeout: a.Timeout, RetryPolicy: a.RetryPolicy}
+	return s.Exchange(ctx, q)
+}
+
+func (a *AuthoritativeResolver) String() string {
+	return "AuthNS"
+}
+
+// Returns the nameservers of the closest zone enclosing name
+func (a *AuthoritativeResolver) nameservers(ctx context.Context, name string) ([]string, error) {
+	for {
+		m := new(dns.Msg)
+		m.SetQuestion(name, dns.TypeNS)
+		r, err := a.Lookup.Exchange(ctx, m)
+		if err != nil {
+			return nil, err
+		}
+		var ret []string
+		for _, rr := range r.Answer {
+			if ns, ok := rr.(*dns.NS); ok && strings.EqualFold(ns.Hdr.Name, name) {
+				ret = append(ret, ns.Ns)
+			}
+		}
+		if len(ret) > 0 {
+			return ret, nil
+		}
+		if name == "." {
+			return nil, errors.New("no authoritative nameservers found")
+		}
+		name = parentName(name)
+	}
+}
+
+// Appends the default DNS port to a server given without one
+func hostPort(server string) string {
+	if _, _, err := net.SplitHostPort(server); err == nil {
+		return server
+	}
+	return net.JoinHostPort(server, "53")
+}
+
+// Returns the name one label above fqdn, e.g. "de." for "bsi.de."
+func parentName(fqdn string) string {
+	fqdn = dns.Fqdn(fqdn)
+	i, end := dns.NextLabel(fqdn, 0)
+	if end || fqdn == "." {
+		return "."
+	}
+	return fqdn[i:]
+}
@@ -0,0 +1,293 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// fakeResolver answers every query from a fixed set of RRs. RRSIGs are
+// returned along with the RRset they cover. Responses without answer carry
+// the negative RRs in the authority section.
+type fakeResolver struct {
+	rrs      []dns.RR
+	negative []dns.RR
+}
+
+func newFakeResolver(t *testing.T, records ...string) *fakeResolver {
+	f := &fakeResolver{}
+	for _, s := range records {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			t.Fatalf("Cannot parse %q: %s", s, err)
+		}
+		f.rrs = append(f.rrs, rr)
+	}
+	return f
+}
+
+func (f *fakeResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	r := new(dns.Msg)
+	r.SetReply(m)
+	q := m.Question[0]
+	for _, rr := range f.rrs {
+		if !strings.EqualFold(rr.Header().Name, q.Name) {
+			continue
+		}
+		if sig, ok := rr.(*dns.RRSIG); ok && sig.TypeCovered == q.Qtype {
+			r.Answer = append(r.Answer, rr)
+		} else if q.Qtype == dns.TypeANY || rr.Header().Rrtype == q.Qtype {
+			r.Answer = append(r.Answer, rr)
+		}
+	}
+	if len(r.Answer) == 0 {
+		r.Ns = f.negative
+	}
+	return r, nil
+}
+
+func TestParentName(t *testing.T) {
+	tests := map[string]string{
+		"www.bsi.de.": "bsi.de.",
+		"bsi.de":      "de.",
+		"de.":         ".",
+		".":           ".",
+	}
+	for in, want := range tests {
+		if got := parentName(in); got != want {
+			t.Errorf("parentName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAuthoritativeResolverNameservers(t *testing.T) {
+	a := &AuthoritativeResolver{Lookup: newFakeResolver(t,
+		"example.com. 3600 IN NS ns1.example.com.",
+		"example.com. 3600 IN NS ns2.example.net.",
+		"com. 3600 IN NS a.gtld-servers.net.",
+	)}
+	ns, err := a.nameservers(context.Background(), "www.example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ns) != 2 || ns[0] != "ns1.example.com." {
+		t.Errorf("Unexpected nameservers for www.example.com.: %v", ns)
+	}
+	ns, err = a.nameservers(context.Background(), parentName("example.com."))
+	if err != nil || len(ns) != 1 || ns[0] != "a.gtld-servers.net." {
+		t.Errorf("Unexpected nameservers for com.: %v (%v)", ns, err)
+	}
+}
+
+func TestCheckNSEC3ExistenceWithFakeResolver(t *testing.T) {
+	in := New(Options{Resolver: newFakeResolver(t,
+		"example.com. 0 IN NSEC3PARAM 1 0 10 AABBCCDD",
+	)})
+	z := &Zone{}
+	if !in.checkNSEC3Existence(context.Background(), z, "example.com") {
+		t.Fatal("NSEC3PARAM not detected")
+	}
+	if !z.NSEC3 || z.NSEC3iter != 10 {
+		t.Errorf("Unexpected zone result: %+v", z)
+	}
+}
+
+func TestFindZoneCuts(t *testing.T) {
+	in := New(Options{Resolver: newFakeResolver(t,
+		"example.co.uk. 3600 IN SOA ns1.example.co.uk. hostmaster.example.co.uk. 1 7200 3600 1209600 3600",
+		"co.uk. 3600 IN NS ns1.nic.uk.",
+		"uk. 3600 IN SOA nsa.nic.uk. hostmaster.nic.uk. 1 900 300 2419200 10800",
+	)})
+	names := pathNames("www.example.co.uk.")
+	path := in.findZoneCuts(context.Background(), names)
+	want := []PathName{
+		{Name: "www.example.co.uk", ZoneCut: false, Zone: "example.co.uk"},
+		{Name: "example.co.uk", ZoneCut: true, Zone: "example.co.uk"},
+		{Name: "co.uk", ZoneCut: true, Zone: "co.uk"},
+		{Name: "uk", ZoneCut: true, Zone: "uk"},
+		{Name: ".", ZoneCut: true, Zone: "."},
+	}
+	if len(path) != len(want) {
+		t.Fatalf("Unexpected path %v", path)
+	}
+	for i := range want {
+		if path[i] != want[i] {
+			t.Errorf("path[%d] = %+v, want %+v", i, path[i], want[i])
+		}
+	}
+}
+
+func TestIterativeResolver(t *testing.T) {
+	// The root delegates example. to 127.0.0.2, which delegates
+	// sub.example. to 127.0.0.3. All servers listen on the same port.
+	var rootQueries int32
+	root, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&rootQueries, 1)
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Qtype == dns.TypeDS && q.Name == "example.":
+			m.Authoritative = true
+			m.Answer = mustRRs(t, "example. 3600 IN DS 1 8 2 AABB")
+		case dns.IsSubDomain("example.", q.Name):
+			m.Ns = mustRRs(t, "example. 3600 IN NS ns.example.", "example. 3600 IN DS 1 8 2 AABB")
+			m.Extra = mustRRs(t, "ns.example. 3600 IN A 127.0.0.2")
+		default:
+			m.Rcode = dns.RcodeNameError
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+	_, port, _ := net.SplitHostPort(root)
+	_, s = startTestServerAt(t, "127.0.0.2:"+port, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		q := r.Question[0]
+		switch {
+		case q.Qtype == dns.TypeDS && q.Name == "sub.example.":
+			m.Authoritative = true
+			m.Answer = mustRRs(t, "sub.example. 3600 IN DS 2 8 2 CCDD")
+		case dns.IsSubDomain("sub.example.", q.Name):
+			m.Ns = mustRRs(t, "sub.example. 3600 IN NS ns.sub.example.")
+			m.Extra = mustRRs(t, "ns.sub.example. 3600 IN A 127.0.0.3")
+		case q.Qtype == dns.TypeDNSKEY:
+			m.Authoritative = true
+			m.Answer = mustRRs(t, "example. 3600 IN DNSKEY 257 3 8 AwEAAQ==")
+		default:
+			m.Authoritative = true
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+	_, s = startTestServerAt(t, "127.0.0.3:"+port, func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		m.Authoritative = true
+		if r.Question[0].Qtype == dns.TypeA {
+			m.Answer = mustRRs(t, r.Question[0].Name+" 3600 IN A 192.0.2.1")
+		}
+		w.WriteMsg(m)
+	})
+	defer s.Shutdown()
+
+	res := &IterativeResolver{RootHints: []string{"127.0.0.1"}, Port: port}
+	trace, err := res.Trace(context.Background(), "www.sub.example", dns.TypeA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trace) != 2 || trace[0].Zone != "example." || trace[0].Parent != "." || trace[1].Zone != "sub.example." {
+		t.Fatalf("Unexpected trace %+v", trace)
+	}
+	if fmt.Sprint(trace[0].DS) != "[1 8 2 AABB]" || fmt.Sprint(trace[0].DNSKEY) != "[257 3 8 AwEAAQ==]" {
+		t.Errorf("Unexpected DS/DNSKEY at example.: %+v", trace[0])
+	}
+	if trace[1].Server != "127.0.0.2:"+port || fmt.Sprint(trace[1].Addresses) != "[127.0.0.3:"+port+"]" {
+		t.Errorf("Unexpected referral to sub.example.: %+v", trace[1])
+	}
+
+	// Cached delegations are used, DS RRs come from the parent zone
+	before := atomic.LoadInt32(&rootQueries)
+	m := new(dns.Msg)
+	m.SetQuestion("sub.example.", dns.TypeDS)
+	r, err := res.Exchange(context.Background(), m)
+	if err != nil || len(r.Answer) != 1 || rdataString(r.Answer[0]) != "2 8 2 CCDD" {
+		t.Errorf("Unexpected DS answer %v (%v)", r, err)
+	}
+	m.SetQuestion("mail.sub.example.", dns.TypeA)
+	if r, err = res.Exchange(context.Background(), m); err != nil || len(r.Answer) != 1 {
+		t.Errorf("Unexpected A answer %v (%v)", r, err)
+	}
+	if n := atomic.LoadInt32(&rootQueries); n != before {
+		t.Errorf("Root queried %d times despite cached delegations", n-before)
+	}
+}
+
+func TestServerResolverRetries(t *testing.T) {
+	// Drops the first two queries
+	var queries int32
+	addr, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		if atomic.AddInt32(&queries, 1) <= 2 {
+			return
+		}
+		testAnswer(w, r)
+	})
+	defer s.Shutdown()
+	health := &ServerHealth{}
+	res := &ServerResolver{Servers: []string{addr}, Timeout: 100 * time.Millisecond,
+		RetryPolicy: RetryPolicy{Retries: 2, Backoff: 10 * time.Millisecond, Health: health}}
+	m := new(dns.Msg)
+	m.SetQuestion("example.", dns.TypeA)
+	if r, err := res.Exchange(context.Background(), m); err != nil || len(r.Answer) != 1 {
+		t.Fatalf("Query not retried: %v (%v)", r, err)
+	}
+	if f := health.Failures(); len(f) != 0 {
+		t.Errorf("Unexpected failures %+v", f)
+	}
+
+	// A server failing repeatedly is marked unreachable and skipped
+	res.Retries = 0
+	atomic.StoreInt32(&queries, -100)
+	for i := 0; i < maxConsecutiveFailures; i++ {
+		if _, err := res.Exchange(context.Background(), m); err == nil {
+			t.Fatal("Dropped query answered")
+		}
+	}
+	f := health.Failures()
+	if len(f) != 1 || !f[0].Unreachable || f[0].Failures != maxConsecutiveFailures {
+		t.Fatalf("Unexpected failures %+v", f)
+	}
+	n := atomic.LoadInt32(&queries)
+	if _, err := res.Exchange(context.Background(), m); err == nil || atomic.LoadInt32(&queries) != n {
+		t.Errorf("Unreachable server queried again (%v)", err)
+	}
+}
+
+func TestServerFailuresPerAudit(t *testing.T) {
+	// Drops every query
+	var queries int32
+	addr, s := startTestServer(t, func(w dns.ResponseWriter, r *dns.Msg) {
+		atomic.AddInt32(&queries, 1)
+	})
+	defer s.Shutdown()
+	in := New(Options{Resolver: &ServerResolver{Servers: []string{addr}, Timeout: 50 * time.Millisecond}})
+	for i := 0; i < 2; i++ {
+		n := atomic.LoadInt32(&queries)
+		res, err := in.Inspect(context.Background(), "example")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if atomic.LoadInt32(&queries) == n {
+			t.Errorf("Audit %d: unreachable server of an earlier audit not queried", i+1)
+		}
+		f := res.ServerFailures
+		if len(f) != 1 || !f[0].Unreachable || f[0].Failures != maxConsecutiveFailures {
+			t.Errorf("Audit %d: unexpected failures %+v", i+1, f)
+		}
+	}
+}
+
+func TestNewKeepsResolver(t *testing.T) {
+	for _, r := range []Resolver{&ServerResolver{Servers: []string{"192.0.2.1"}}, &IterativeResolver{}} {
+		New(Options{Resolver: r, Retries: 5})
+		var p RetryPolicy
+		switch r := r.(type) {
+		case *ServerResolver:
+			p = r.RetryPolicy
+		case *IterativeResolver:
+			p = r.RetryPolicy
+		}
+		if p != (RetryPolicy{}) {
+			t.Errorf("%T modified: %+v", r, p)
+		}
+	}
+	if p := (Options{Retries: -1}).RetryPolicy(); p.Retries != 0 || p.Backoff != DefaultBackoff {
+		t.Errorf("Unexpected retry policy %+v", p)
+	}
+}
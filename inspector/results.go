@@ -0,0 +1,243 @@
+package inspector
+
+// Result is the  struct for merging all results found in an audit
+type Result struct {
+	Target                string       `json:"target"`
+	EvaluatedAt           string       `json:"evaluatedAt"`
+	DNSSEC                bool         `json:"dnssec"`
+	Status                string       `json:"status"`
+	TrustIsland           bool         `json:"trustIsland"`
+	TrustIslandAnchorZone string       `json:"trustIslandAnchorZone,omitempty"`
+	RecommendedDS         []string     `json:"recommendedDS,omitempty"`
+	Zones                 []Zone       `json:"zones"`
+	Path                  []PathName   `json:"path"`
+	Referrals             []Referral   `json:"referrals,omitempty"`
+	Servers               []ServerView `json:"servers,omitempty"`
+	// Servers with failed exchanges during this audit
+	ServerFailures []ServerFailure `json:"serverFailures,omitempty"`
+	// Findings lists every problem of the audit (see collectFindings)
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// PathName describes a name between the target and the root. Names without
+// a zone cut are only labels inside the zone given by Zone.
+type PathName struct {
+	Name    string `json:"name"`
+	ZoneCut bool   `json:"zoneCut"`
+	Zone    string `json:"zone"`
+}
+
+// Zone describes a single zone file
+type Zone struct {
+	FQDN                  string           `json:"fqdn"`
+	Status                string           `json:"status"`
+	StatusReason          string           `json:"statusReason,omitempty"`
+	Validation            bool             `json:"validation"`
+	ValidatesAnswer       bool             `json:"validatesAnswer"`
+	ValidatesNs           bool             `json:"validatesNs"`
+	ValidatesExtra        bool             `json:"validatesExtra"`
+	ValidationErrorAnswer string           `json:"validationErrorAnswer,omitempty"`
+	ValidationErrorNs     string           `json:"validationErrorNs,omitempty"`
+	ValidationErrorExtra  string           `json:"validationErrorExtra,omitempty"`
+	NSEC3                 bool             `json:"nsec3"`
+	NSEC3iter             int              `json:"nsec3iter"`
+	Denial                *Denial          `json:"denial,omitempty"`
+	Enumeration           *Enumeration     `json:"enumeration,omitempty"`
+	NSEC3Parameters       *NSEC3Parameters `json:"nsec3Parameters,omitempty"`
+	KeyCount              int              `json:"keycount"`
+	RunningRollover       bool             `json:"runningRollover,omitempty"`
+	Rollover              *Rollover        `json:"rollover,omitempty"`
+	Keys                  []Key            `json:"keys,omitempty"`
+	Signatures            []Signature      `json:"signatures,omitempty"`
+	SignatureWarning      bool             `json:"signatureWarning,omitempty"`
+	DS                    []DSRecord       `json:"ds,omitempty"`
+	DSIssues              []string         `json:"dsIssues,omitempty"`
+	CDS                   *CDS             `json:"cds,omitempty"`
+	ResponseSizes         *ResponseSizes   `json:"responseSizes,omitempty"`
+	AutoritativeNS        []Nameserver     `json:"authoritativeNS,omitempty"`
+	Inconsistent          bool             `json:"inconsistent"`
+	Inconsistencies       []string         `json:"inconsistencies,omitempty"`
+
+	// Name queried for the NXDOMAIN responses of the zone
+	probe string
+}
+
+// Denial describes the authenticated denial of existence of a zone, tested
+// with an NXDOMAIN and a NODATA response
+type Denial struct {
+	Method   string `json:"method"`
+	NXDOMAIN bool   `json:"nxdomain"`
+	// Compact is set if non-existent names are denied by the compact
+	// NOERROR responses of RFC 9824
+	Compact       bool   `json:"compact,omitempty"`
+	NODATA        bool   `json:"nodata"`
+	NXDOMAINError string `json:"nxdomainError,omitempty"`
+	NODATAError   string `json:"nodataError,omitempty"`
+}
+
+// NSEC3Parameters rates the NSEC3 parameters of a zone by RFC 9276. Param is
+// the NSEC3PARAM RR, Served the parameters of the NSEC3 RRs served (hash
+// algorithm, flags without opt-out, iterations, salt).
+type NSEC3Parameters struct {
+	Param          string   `json:"param,omitempty"`
+	Served         string   `json:"served,omitempty"`
+	Iterations     int      `json:"iterations"`
+	SaltLength     int      `json:"saltLength"`
+	OptOut         bool     `json:"optOut"`
+	Compliant      bool     `json:"compliant"`
+	LikelyInsecure bool     `json:"likelyInsecure,omitempty"`
+	Issues         []string `json:"issues,omitempty"`
+}
+
+// Enumeration describes how far the names of a zone are exposed by its
+// denial of existence. Names and ExposedNames are set if the NSEC chain was
+// walked; Complete tells if the walk returned to the apex.
+type Enumeration struct {
+	Method        string   `json:"method"`
+	Risk          string   `json:"risk"`
+	Walked        bool     `json:"walked,omitempty"`
+	Complete      bool     `json:"complete,omitempty"`
+	ExposedNames  int      `json:"exposedNames,omitempty"`
+	Names         []string `json:"names,omitempty"`
+	WalkError     string   `json:"walkError,omitempty"`
+	HashAlgorithm string   `json:"hashAlgorithm,omitempty"`
+	SaltLength    int      `json:"saltLength,omitempty"`
+	Iterations    int      `json:"iterations,omitempty"`
+	OptOut        bool     `json:"optOut,omitempty"`
+}
+
+// Rollover describes the key rollovers a zone appears to be in and whether
+// the current state is safe (RFC 6781, RFC 7583)
+type Rollover struct {
+	Phases []string `json:"phases,omitempty"`
+	Safe   bool     `json:"safe"`
+	Issues []string `json:"issues,omitempty"`
+}
+
+// Nameserver describes the important facts for a namerserver
+type Nameserver struct {
+	Name      string    `json:"name"`
+	Addresses []Address `json:"addresses"`
+	Resolver  bool      `json:"resolver"`
+	EDNS0     bool      `json:"edns0"`
+}
+
+// Address describes the results for a single IPv4 or IPv6 address of a
+// nameserver
+type Address struct {
+	IP        string `json:"ip"`
+	IPv6      bool   `json:"ipv6"`
+	Reachable bool   `json:"reachable"`
+	EDNS0     bool   `json:"edns0"`
+	DNSSEC    bool   `json:"dnssec"`
+	Error     string `json:"error,omitempty"`
+	// Results of the zone checks against this address only
+	SOASerial       uint32   `json:"soaSerial"`
+	KeyTags         []uint16 `json:"keyTags,omitempty"`
+	NSEC3PARAM      string   `json:"nsec3param,omitempty"`
+	CDS             []string `json:"cds,omitempty"`
+	CDNSKEY         []string `json:"cdnskey,omitempty"`
+	Validation      bool     `json:"validation"`
+	ValidationError string   `json:"validationError,omitempty"`
+	// Results of the open resolver check
+	Resolver           bool `json:"resolver"`
+	RecursionAvailable bool `json:"recursionAvailable"`
+	CacheSnooping      bool `json:"cacheSnooping"`
+	RALeak             bool `json:"raLeak"`
+}
+
+// Key struct contains all valuable information about a single DNSKEY RR
+type Key struct {
+	Verifiable  bool         `json:"valid"`
+	TrustAnchor bool         `json:"trustAnchor"`
+	Type        string       `json:"type"`
+	Hash        string       `json:"hash"`
+	HComment    string       `json:"hComment"`
+	HUntil      string       `json:"hUntil"`
+	Alg         string       `json:"alg"`
+	KeyLength   int          `json:"keyLength"`
+	AComment    string       `json:"aComment"`
+	AUntil      string       `json:"aUntil"`
+	Policy      string       `json:"policy,omitempty"`
+	Compliance  []Compliance `json:"compliance,omitempty"`
+	// Revoked is set for keys with the REVOKE flag (RFC 5011)
+	Revoked bool `json:"revoked,omitempty"`
+	// DS RRs to submit to the parent for KSKs not matched by a DS RR
+	RecommendedDS []string `json:"recommendedDS,omitempty"`
+	// Error describes malformed key material
+	Error string `json:"error,omitempty"`
+}
+
+// Compliance is the verdict of one policy for a key or DS RR
+type Compliance struct {
+	Policy   string `json:"policy"`
+	HComment string `json:"hComment,omitempty"`
+	HUntil   string `json:"hUntil,omitempty"`
+	AComment string `json:"aComment,omitempty"`
+	AUntil   string `json:"aUntil,omitempty"`
+}
+
+// Signature is the validity period of an RRSIG RR. Remaining is negative
+// for expired signatures, TTL is the original TTL of the covered RRset.
+type Signature struct {
+	Name        string   `json:"name"`
+	TypeCovered string   `json:"typeCovered"`
+	KeyTag      uint16   `json:"keyTag"`
+	Inception   string   `json:"inception"`
+	Expiration  string   `json:"expiration"`
+	Remaining   string   `json:"remaining"`
+	TTL         uint32   `json:"ttl"`
+	Warnings    []string `json:"warnings,omitempty"`
+}
+
+// CDS is the result of the CDS/CDNSKEY checks of a child zone. DS lists the
+// DS RRs the parent should publish if the RRsets are accepted, Delete is the
+// RFC 8078 signal to remove all DS RRs.
+type CDS struct {
+	CDS          []string `json:"cds,omitempty"`
+	CDNSKEY      []string `json:"cdnskey,omitempty"`
+	Signed       bool     `json:"signed"`
+	Consistent   bool     `json:"consistent"`
+	Delete       bool     `json:"delete,omitempty"`
+	Accepted     bool     `json:"accepted"`
+	UpdateNeeded bool     `json:"updateNeeded,omitempty"`
+	DS           []string `json:"ds,omitempty"`
+	Issues       []string `json:"issues,omitempty"`
+}
+
+// DSRecord is a DS RR of a zone at its parent. The verdict fields rate the
+// digest type.
+type DSRecord struct {
+	KeyTag     uint16       `json:"keyTag"`
+	Algorithm  string       `json:"algorithm"`
+	DigestType string       `json:"digestType"`
+	Digest     string       `json:"digest"`
+	MatchesKey bool         `json:"matchesKey"`
+	HComment   string       `json:"hComment"`
+	HUntil     string       `json:"hUntil"`
+	Policy     string       `json:"policy,omitempty"`
+	Compliance []Compliance `json:"compliance,omitempty"`
+}
+
+// ResponseSizes are the sizes of the DNSKEY, ANY and NXDOMAIN responses of
+// a zone measured at one of its authoritative nameservers
+type ResponseSizes struct {
+	Server            string         `json:"server"`
+	Sizes             []ResponseSize `json:"sizes"`
+	FragmentationRisk bool           `json:"fragmentationRisk"`
+	Issues            []string       `json:"issues,omitempty"`
+}
+
+// ResponseSize is the size of one response in bytes
+type ResponseSize struct {
+	Query string `json:"query"`
+	// Size of the complete response received over TCP
+	Size int `json:"size"`
+	// Buffer sizes of BufferSizes the response does not fit in
+	Exceeds []int `json:"exceeds,omitempty"`
+	// Size of the UDP response to a buffer size of 4096 bytes and whether
+	// it was truncated
+	UDPSize   int    `json:"udpSize"`
+	Truncated bool   `json:"truncated"`
+	Error     string `json:"error,omitempty"`
+}
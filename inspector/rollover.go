@@ -0,0 +1,163 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/miekg/dns"
+)
+
+// Rollover phases as described in RFC 6781 section 4.1 and RFC 7583
+const (
+	PhaseZSKPrePublish      = "zsk-pre-publish"
+	PhaseZSKDoubleSignature = "zsk-double-signature"
+	PhaseKSKPrePublish      = "ksk-pre-publish"
+	PhaseKSKDoubleSignature = "ksk-double-signature"
+	PhaseKSKDoubleDS        = "ksk-double-ds"
+	PhaseKSKRevoke          = "ksk-revoke"
+	PhaseAlgorithmRollover  = "algorithm-rollover"
+)
+
+// Detects running key rollovers of a signed zone from its DNSKEY RRset, the
+// key tags of the RRSIGs over the DNSKEY and SOA RRsets and the DS RRset of
+// the parent zone.
+func (in *Inspector) checkRollover(ctx context.Context, z *Zone) {
+	keys, keySigs := in.getDNSKEYset(ctx, z.FQDN)
+	if len(keys) == 0 {
+		return
+	}
+	m := in.dnssecQuery(ctx, z.FQDN, dns.TypeSOA, in.resolver)
+	soaSigs := getRRsigs(m, dns.TypeSOA)
+	var ds []dns.RR
+	if z.FQDN != "." {
+		ds, _, _, _ = in.getDSset(ctx, z.FQDN)
+	}
+	z.Rollover = detectRollover(keys, keySigs, soaSigs, ds)
+	z.RunningRollover = len(z.Rollover.Phases) > 0
+}
+
+// Derives the rollover phases from a snapshot of the zone
+//   - more ZSKs than ZSKs signing the SOA RR: pre-publish
+//   - more than one ZSK signing the SOA RR: double-signature
+//   - DS RRs for more than one key at the parent: double-DS
+//   - more than one KSK signing the DNSKEY RRset: double-signature (KSK)
+//   - more KSKs than KSKs signing the DNSKEY RRset: pre-publish (KSK)
+//   - a key with the REVOKE bit (RFC 5011)
+//   - keys of more than one algorithm: algorithm rollover
+//
+// and checks whether the current state is safe, i.e. validates for resolvers
+// using either the old or the new keys.
+func detectRollover(keys []*dns.DNSKEY, keySigs []*dns.RRSIG, soaSigs []*dns.RRSIG, ds []dns.RR) *Rollover {
+	r := &Rollover{}
+	var zsks, ksks, signingZSKs, signingKSKs []*dns.DNSKEY
+	algs := map[uint8]bool{}
+	revoked := false
+	for _, k := range keys {
+		if k.Flags&dns.REVOKE != 0 {
+			revoked = true
+			continue
+		}
+		algs[k.Algorithm] = true
+		if k.Flags&dns.SEP != 0 {
+			ksks = append(ksks, k)
+			if signedBy(keySigs, k) {
+				signingKSKs = append(signingKSKs, k)
+			}
+		} else {
+			zsks = append(zsks, k)
+			if signedBy(soaSigs, k) {
+				signingZSKs = append(signingZSKs, k)
+			}
+		}
+	}
+	dsTags := map[uint16]bool{}
+	for _, rr := range ds {
+		if d, ok := rr.(*dns.DS); ok {
+			dsTags[d.KeyTag] = true
+		}
+	}
+
+	if len(signingZSKs) > 1 {
+		r.Phases = append(r.Phases, PhaseZSKDoubleSignature)
+	} else if len(zsks) > 1 {
+		r.Phases = append(r.Phases, PhaseZSKPrePublish)
+	}
+	if len(dsTags) > 1 {
+		r.Phases = append(r.Phases, PhaseKSKDoubleDS)
+	}
+	if len(signingKSKs) > 1 {
+		r.Phases = append(r.Phases, PhaseKSKDoubleSignature)
+	} else if len(ksks) > 1 && len(dsTags) < 2 {
+		r.Phases = append(r.Phases, PhaseKSKPrePublish)
+	}
+	if revoked {
+		r.Phases = append(r.Phases, PhaseKSKRevoke)
+	}
+	if len(algs) > 1 {
+		r.Phases = append(r.Phases, PhaseAlgorithmRollover)
+	}
+
+	// RFC 4035 section 2.2 / RFC 6781 section 4.1.4: every algorithm in the
+	// DNSKEY RRset has to sign the zone data and the DNSKEY RRset
+	var sorted []int
+	for alg := range algs {
+		sorted = append(sorted, int(alg))
+	}
+	sort.Ints(sorted)
+	for _, a := range sorted {
+		alg := uint8(a)
+		if !signedWithAlgorithm(keySigs, alg) || !signedWithAlgorithm(soaSigs, alg) {
+			r.Issues = append(r.Issues, fmt.Sprintf("algorithm %s is published but does not sign the DNSKEY and SOA RRsets", dns.AlgorithmToString[alg]))
+		}
+	}
+	for _, sig := range soaSigs {
+		if findKey(keys, sig.KeyTag, sig.Algorithm) == nil {
+			r.Issues = append(r.Issues, fmt.Sprintf("SOA RR is signed by key %d which is not published", sig.KeyTag))
+		}
+	}
+	if len(ds) > 0 {
+		matched := false
+		for _, rr := range ds {
+			for _, k := range signingKSKs {
+				if d, ok := rr.(*dns.DS); ok && dsMatchesKey(d, k) {
+					matched = true
+				}
+			}
+		}
+		if !matched {
+			r.Issues = append(r.Issues, "no DS RR matches a KSK signing the DNSKEY RRset")
+		}
+	}
+	r.Safe = len(r.Issues) == 0
+	return r
+}
+
+// Checks if one of the RRSIGs is made by key
+func signedBy(sigs []*dns.RRSIG, key *dns.DNSKEY) bool {
+	for _, s := range sigs {
+		if s.KeyTag == key.KeyTag() && s.Algorithm == key.Algorithm {
+			return true
+		}
+	}
+	return false
+}
+
+func signedWithAlgorithm(sigs []*dns.RRSIG, alg uint8) bool {
+	for _, s := range sigs {
+		if s.Algorithm == alg {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the key with the given key tag and algorithm or nil
+func findKey(keys []*dns.DNSKEY, tag uint16, alg uint8) *dns.DNSKEY {
+	for _, k := range keys {
+		if k.KeyTag() == tag && k.Algorithm == alg {
+			return k
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package inspector
+
+import (
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestDetectRollover(t *testing.T) {
+	ksk := newTestZone(t, "example.")
+	zsk := newTestZone(t, "example.")
+	zsk.key.Flags = ZSK
+	newZSK := newTestZone(t, "example.")
+	newZSK.key.Flags = ZSK
+	newKSK := newTestZone(t, "example.")
+	soa := mustRRs(t, "example. 3600 IN SOA ns1.example. hostmaster.example. 1 7200 3600 1209600 3600")
+	ds := ksk.key.ToDS(dns.SHA256)
+	newDS := newKSK.key.ToDS(dns.SHA256)
+
+	tests := []struct {
+		name    string
+		keys    []*dns.DNSKEY
+		signers []*testZone // signing the SOA RR
+		ksks    []*testZone // signing the DNSKEY RRset
+		ds      []dns.RR
+		phases  []string
+		safe    bool
+	}{
+		{"steady state", []*dns.DNSKEY{ksk.key, zsk.key}, []*testZone{zsk}, []*testZone{ksk}, []dns.RR{ds}, nil, true},
+		{"zsk pre-publish", []*dns.DNSKEY{ksk.key, zsk.key, newZSK.key}, []*testZone{zsk}, []*testZone{ksk}, []dns.RR{ds},
+			[]string{PhaseZSKPrePublish}, true},
+		{"zsk double-signature", []*dns.DNSKEY{ksk.key, zsk.key, newZSK.key}, []*testZone{zsk, newZSK}, []*testZone{ksk}, []dns.RR{ds},
+			[]string{PhaseZSKDoubleSignature}, true},
+		{"ksk double-ds", []*dns.DNSKEY{ksk.key, zsk.key}, []*testZone{zsk}, []*testZone{ksk}, []dns.RR{ds, newDS},
+			[]string{PhaseKSKDoubleDS}, true},
+		{"ksk double-signature", []*dns.DNSKEY{ksk.key, newKSK.key, zsk.key}, []*testZone{zsk}, []*testZone{ksk, newKSK}, []dns.RR{ds},
+			[]string{PhaseKSKDoubleSignature}, true},
+		{"ksk swapped too early", []*dns.DNSKEY{newKSK.key, zsk.key}, []*testZone{zsk}, []*testZone{newKSK}, []dns.RR{ds},
+			nil, false},
+	}
+	for _, test := range tests {
+		var keySigs, soaSigs []*dns.RRSIG
+		keyset := keysToRRs(test.keys)
+		for _, z := range test.ksks {
+			keySigs = append(keySigs, z.sign(t, keyset...))
+		}
+		for _, z := range test.signers {
+			soaSigs = append(soaSigs, z.sign(t, soa...))
+		}
+		r := detectRollover(test.keys, keySigs, soaSigs, test.ds)
+		if len(r.Phases) != len(test.phases) {
+			t.Errorf("%s: phases %v, want %v", test.name, r.Phases, test.phases)
+		} else {
+			for i := range r.Phases {
+				if r.Phases[i] != test.phases[i] {
+					t.Errorf("%s: phases %v, want %v", test.name, r.Phases, test.phases)
+				}
+			}
+		}
+		if r.Safe != test.safe {
+			t.Errorf("%s: safe = %v, issues %v", test.name, r.Safe, r.Issues)
+		}
+	}
+}
+
+func TestDetectAlgorithmRollover(t *testing.T) {
+	ksk := newTestZone(t, "example.")
+	rsa := &dns.DNSKEY{Hdr: ksk.key.Hdr, Flags: KSK, Protocol: 3, Algorithm: dns.RSASHA256}
+	if _, err := rsa.Generate(1024); err != nil {
+		t.Fatal(err)
+	}
+	keys := []*dns.DNSKEY{ksk.key, rsa}
+	keySigs := []*dns.RRSIG{ksk.sign(t, keysToRRs(keys)...)}
+	soa := mustRRs(t, "example. 3600 IN SOA ns1.example. hostmaster.example. 1 7200 3600 1209600 3600")
+	soaSigs := []*dns.RRSIG{ksk.sign(t, soa...)}
+	r := detectRollover(keys, keySigs, soaSigs, nil)
+	found := false
+	for _, p := range r.Phases {
+		found = found || p == PhaseAlgorithmRollover
+	}
+	if !found || r.Safe {
+		t.Errorf("Unsigned new algorithm not reported: %+v", r)
+	}
+}
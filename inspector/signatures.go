@@ -0,0 +1,84 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Collects the RRSIGs of the SOA, DNSKEY and NS RRsets of the zone apex and
+// reports their validity periods. The RRsets are queried one at a time, as
+// many servers answer ANY queries minimally (RFC 8482).
+func (in *Inspector) checkSignatures(ctx context.Context, z *Zone) {
+	var sigs []*dns.RRSIG
+	for _, t := range []uint16{dns.TypeSOA, dns.TypeDNSKEY, dns.TypeNS} {
+		m := in.dnssecQuery(ctx, z.FQDN, t, in.auth)
+		for _, rr := range m.Answer {
+			s, ok := rr.(*dns.RRSIG)
+			if ok && s.TypeCovered == t && equalNames(s.Hdr.Name, z.FQDN) && !containsSig(sigs, s) {
+				sigs = append(sigs, s)
+			}
+		}
+	}
+	z.Signatures = evaluateSignatures(sigs, in.now(), in.opts.ExpiryWarning)
+	for _, s := range z.Signatures {
+		if len(s.Warnings) > 0 {
+			z.SignatureWarning = true
+		}
+	}
+}
+
+// Reports inception, expiration and remaining validity of each RRSIG at now.
+// A signature is flagged if it is not valid at now, expires within window or
+// remains valid for less than the original TTL of the RRset it covers: a
+// resolver caching the RRset that long would hold an expired signature.
+func evaluateSignatures(sigs []*dns.RRSIG, now time.Time, window time.Duration) (ret []Signature) {
+	for _, sig := range sigs {
+		inception := sigTime(sig.Inception, now)
+		expiration := sigTime(sig.Expiration, now)
+		remaining := expiration.Sub(now)
+		s := Signature{
+			Name:        sig.Hdr.Name,
+			TypeCovered: dns.TypeToString[sig.TypeCovered],
+			KeyTag:      sig.KeyTag,
+			Inception:   inception.Format(time.RFC3339),
+			Expiration:  expiration.Format(time.RFC3339),
+			Remaining:   remaining.String(),
+			TTL:         sig.OrigTtl,
+		}
+		ttl := time.Duration(sig.OrigTtl) * time.Second
+		switch {
+		case now.Before(inception):
+			s.Warnings = append(s.Warnings, "signature is not yet valid")
+		case remaining <= 0:
+			s.Warnings = append(s.Warnings, "signature expired")
+		default:
+			if remaining < window {
+				s.Warnings = append(s.Warnings, fmt.Sprintf("signature expires within %s", window))
+			}
+			if remaining < ttl {
+				s.Warnings = append(s.Warnings, fmt.Sprintf("remaining validity is shorter than the TTL of %ds", sig.OrigTtl))
+			}
+		}
+		ret = append(ret, s)
+	}
+	return
+}
+
+// Converts an RRSIG timestamp to the time closest to now, as the timestamps
+// wrap around every 136 years (RFC 4034 section 3.1.5, serial arithmetic)
+func sigTime(t uint32, now time.Time) time.Time {
+	n := now.Unix()
+	return time.Unix(n+int64(int32(t-uint32(n))), 0).UTC()
+}
+
+func containsSig(sigs []*dns.RRSIG, sig *dns.RRSIG) bool {
+	for _, s := range sigs {
+		if s.TypeCovered == sig.TypeCovered && s.KeyTag == sig.KeyTag && equalNames(s.Hdr.Name, sig.Hdr.Name) {
+			return true
+		}
+	}
+	return false
+}
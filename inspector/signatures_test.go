@@ -0,0 +1,84 @@
+package inspector
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+func TestEvaluateSignatures(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	newSig := func(inception, expiration time.Duration, ttl uint32) *dns.RRSIG {
+		return &dns.RRSIG{
+			Hdr:         dns.RR_Header{Name: "example.", Rrtype: dns.TypeRRSIG, Class: dns.ClassINET, Ttl: ttl},
+			TypeCovered: dns.TypeSOA,
+			KeyTag:      12345,
+			OrigTtl:     ttl,
+			Inception:   uint32(now.Add(inception).Unix()),
+			Expiration:  uint32(now.Add(expiration).Unix()),
+		}
+	}
+	tests := []struct {
+		name     string
+		sig      *dns.RRSIG
+		warnings []string
+	}{
+		{"valid", newSig(-time.Hour, 30*24*time.Hour, 3600), nil},
+		{"expiring", newSig(-time.Hour, 48*time.Hour, 3600), []string{"signature expires within 168h0m0s"}},
+		{"ttl", newSig(-time.Hour, 30*24*time.Hour, 60*86400),
+			[]string{"remaining validity is shorter than the TTL of 5184000s"}},
+		{"expired", newSig(-48*time.Hour, -time.Hour, 3600), []string{"signature expired"}},
+		{"not yet valid", newSig(time.Hour, 48*time.Hour, 3600), []string{"signature is not yet valid"}},
+	}
+	for _, tt := range tests {
+		s := evaluateSignatures([]*dns.RRSIG{tt.sig}, now, DefaultExpiryWarning)[0]
+		if !reflect.DeepEqual(s.Warnings, tt.warnings) {
+			t.Errorf("%s: got warnings %v, want %v", tt.name, s.Warnings, tt.warnings)
+		}
+	}
+	s := evaluateSignatures([]*dns.RRSIG{tests[0].sig}, now, DefaultExpiryWarning)[0]
+	if s.TypeCovered != "SOA" || s.Expiration != "2024-03-31T12:00:00Z" || s.Remaining != "720h0m0s" {
+		t.Errorf("got %+v", s)
+	}
+}
+
+// minimalANY answers ANY queries with a synthesized HINFO RR (RFC 8482)
+type minimalANY struct {
+	*fakeResolver
+}
+
+func (r minimalANY) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	if m.Question[0].Qtype != dns.TypeANY {
+		return r.fakeResolver.Exchange(ctx, m)
+	}
+	resp := new(dns.Msg)
+	resp.SetReply(m)
+	rr, _ := dns.NewRR(m.Question[0].Name + ` 3600 IN HINFO "RFC8482" ""`)
+	resp.Answer = append(resp.Answer, rr)
+	return resp, nil
+}
+
+func TestCheckSignatures(t *testing.T) {
+	fake := minimalANY{newFakeResolver(t,
+		"example. 3600 IN SOA ns.example. admin.example. 1 7200 3600 1209600 3600",
+		"example. 3600 IN RRSIG SOA 13 1 3600 20300101000000 20200101000000 1 example. AA==",
+		"example. 3600 IN DNSKEY 257 3 13 AA==",
+		"example. 3600 IN RRSIG DNSKEY 13 1 3600 20300101000000 20200101000000 2 example. AA==",
+		"example. 3600 IN NS ns.example.",
+		"example. 3600 IN RRSIG NS 13 1 3600 20300101000000 20200101000000 1 example. AA==",
+	)}
+	in := New(Options{Resolver: fake})
+	in.auth = fake
+	z := &Zone{FQDN: "example"}
+	in.checkSignatures(context.Background(), z)
+	var covered []string
+	for _, s := range z.Signatures {
+		covered = append(covered, s.TypeCovered)
+	}
+	if want := []string{"SOA", "DNSKEY", "NS"}; !reflect.DeepEqual(covered, want) {
+		t.Errorf("Got signatures of %v, want %v", covered, want)
+	}
+}
@@ -0,0 +1,119 @@
+package inspector
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// BufferSizes are the EDNS buffer sizes the responses of a zone are
+// compared with. 1232 bytes is the default recommended by DNS Flag Day 2020
+// to avoid IP fragmentation.
+var BufferSizes = []int{1232, 1400, 4096}
+
+// flagDayBufferSize is the largest UDP response DNS Flag Day 2020
+// recommends to send without risking IP fragmentation
+const flagDayBufferSize = 1232
+
+// Measures the sizes of the DNSKEY, ANY and NXDOMAIN responses of a zone.
+// Each query is sent to the first reachable authoritative nameserver over
+// TCP for the full size and over UDP with a buffer size of 4096 bytes to
+// see whether the server truncates large responses or sends them in
+// fragments.
+func (in *Inspector) checkResponseSizes(ctx context.Context, z *Zone) {
+	var ip string
+	for i := range z.AutoritativeNS {
+		if addrs := z.AutoritativeNS[i].reachableAddresses(); len(addrs) > 0 {
+			ip = addrs[0]
+			break
+		}
+	}
+	if ip == "" {
+		return
+	}
+	addr := net.JoinHostPort(ip, "53")
+	tcp := in.server("tcp://" + addr)
+	queries := []struct {
+		query string
+		name  string
+		t     uint16
+	}{
+		{"DNSKEY", dns.Fqdn(z.FQDN), dns.TypeDNSKEY},
+		{"ANY", dns.Fqdn(z.FQDN), dns.TypeANY},
+		{"NXDOMAIN", z.nxdomainProbe(), dns.TypeA},
+	}
+	rs := &ResponseSizes{Server: ip}
+	for _, q := range queries {
+		m := new(dns.Msg)
+		m.SetQuestion(q.name, q.t)
+		m.SetEdns0(4096, true)
+		s := ResponseSize{Query: q.query}
+		r, err := in.exchange(ctx, tcp, m)
+		if err != nil {
+			s.Error = err.Error()
+			rs.Sizes = append(rs.Sizes, s)
+			continue
+		}
+		s.Size = wireSize(r)
+		// The resolver retries truncated responses over TCP, so the UDP
+		// response is fetched with a plain client
+		if r, err := in.exchange(ctx, udpResolver{addr, in.opts.Timeout}, m); err == nil {
+			s.UDPSize, s.Truncated = wireSize(r), r.Truncated
+		}
+		rs.Sizes = append(rs.Sizes, s)
+	}
+	evaluateResponseSizes(rs)
+	z.ResponseSizes = rs
+}
+
+// udpResolver sends a single UDP query and does not retry truncated
+// responses over TCP
+type udpResolver struct {
+	addr    string
+	timeout time.Duration
+}
+
+// Exchange implements Resolver
+func (u udpResolver) Exchange(ctx context.Context, m *dns.Msg) (*dns.Msg, error) {
+	c := &dns.Client{Timeout: u.timeout}
+	r, _, err := c.ExchangeContext(ctx, m, u.addr)
+	return r, err
+}
+
+func (u udpResolver) String() string {
+	return "udp://" + u.addr
+}
+
+// Returns the size of a response on the wire, assuming name compression
+func wireSize(r *dns.Msg) int {
+	r.Compress = true
+	return r.Len()
+}
+
+// Compares the response sizes with BufferSizes and flags responses sent in
+// UDP datagrams larger than recommended by DNS Flag Day 2020
+func evaluateResponseSizes(rs *ResponseSizes) {
+	for i := range rs.Sizes {
+		s := &rs.Sizes[i]
+		if s.Size == 0 {
+			continue
+		}
+		s.Exceeds = nil
+		for _, b := range BufferSizes {
+			if s.Size > b {
+				s.Exceeds = append(s.Exceeds, b)
+			}
+		}
+		if s.UDPSize > flagDayBufferSize && !s.Truncated {
+			rs.FragmentationRisk = true
+			rs.Issues = append(rs.Issues, fmt.Sprintf("%s response of %d bytes is sent over UDP, responses above %d bytes risk IP fragmentation (DNS Flag Day 2020)",
+				s.Query, s.UDPSize, flagDayBufferSize))
+		}
+		if s.Size > BufferSizes[len(BufferSizes)-1] {
+			rs.Issues = append(rs.Issues, fmt.Sprintf("%s response of %d bytes exceeds every common buffer size and needs TCP", s.Query, s.Size))
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package inspector
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEvaluateResponseSizes(t *testing.T) {
+	rs := &ResponseSizes{Sizes: []ResponseSize{
+		{Query: "DNSKEY", Size: 1500, UDPSize: 1500},
+		{Query: "ANY", Size: 5000, UDPSize: 1100, Truncated: true},
+		{Query: "NXDOMAIN", Size: 900, UDPSize: 900},
+		{Query: "DNSKEY", Error: "timeout"},
+	}}
+	evaluateResponseSizes(rs)
+	if !rs.FragmentationRisk || len(rs.Issues) != 2 {
+		t.Errorf("Unexpected issues %q", rs.Issues)
+	}
+	want := []string{"[1232 1400]", "[1232 1400 4096]", "[]", "[]"}
+	for i, s := range rs.Sizes {
+		if fmt.Sprint(s.Exceeds) != want[i] {
+			t.Errorf("%s: exceeds %v, want %s", s.Query, s.Exceeds, want[i])
+		}
+	}
+
+	// Large responses truncated over UDP are fine
+	rs = &ResponseSizes{Sizes: []ResponseSize{{Query: "DNSKEY", Size: 1500, UDPSize: 100, Truncated: true}}}
+	evaluateResponseSizes(rs)
+	if rs.FragmentationRisk || len(rs.Issues) != 0 {
+		t.Errorf("Unexpected issues %q", rs.Issues)
+	}
+}
@@ -0,0 +1,293 @@
+package inspector
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// Transports a server can be queried over, selected by the scheme of the
+// server address
+const (
+	TransportUDP   = "udp"
+	TransportTCP   = "tcp"
+	TransportTLS   = "tls"   // DNS over TLS, RFC 7858
+	TransportHTTPS = "https" // DNS over HTTPS, RFC 8484
+)
+
+// dohMediaType is the media type of DNS messages sent over HTTPS
+const dohMediaType = "application/dns-message"
+
+// httpIdleTimeout closes the idle connections of DNS over HTTPS servers
+const httpIdleTimeout = 30 * time.Second
+
+// server is a parsed server address of a ServerResolver
+type server struct {
+	transport string
+	// addr is host:port, or the URL for DNS over HTTPS
+	addr string
+	tls  *tls.Config
+	// http is the client of a DNS over HTTPS server
+	http *http.Client
+}
+
+// Parses a server address given as host, host:port or URL. The scheme
+// selects the transport:
+//
+//	udp://host[:port]          UDP, the default for addresses without scheme
+//	tcp://host[:port]          TCP
+//	tls://host[:port]          DNS over TLS, port 853 by default
+//	https://host[:port]/path   DNS over HTTPS
+//
+// The fragment of a TLS or HTTPS address configures the TLS connection as
+// comma separated key=value pairs:
+//
+//	name=dns.example          server name to verify the certificate for
+//	pin-sha256=BASE64         SHA-256 digest of the SubjectPublicKeyInfo of
+//	                          the server certificate (RFC 7469)
+//	cert-sha256=HEX           SHA-256 digest of the server certificate
+//
+// If pins are given the server certificate has to match one of them instead
+// of being verified against the system roots. DNS over HTTPS servers get
+// an HTTP client of their own, idle connections are closed after
+// httpIdleTimeout.
+func parseServer(s string) (*server, error) {
+	if !strings.Contains(s, "://") {
+		return &server{transport: TransportUDP, addr: hostPort(s)}, nil
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	srv := &server{transport: strings.ToLower(u.Scheme)}
+	switch srv.transport {
+	case TransportUDP, TransportTCP:
+		srv.addr = hostPort(u.Host)
+		return srv, nil
+	case TransportTLS:
+		srv.addr = u.Host
+		if u.Port() == "" {
+			srv.addr = net.JoinHostPort(u.Hostname(), "853")
+		}
+	case TransportHTTPS:
+		srv.addr = (&url.URL{Scheme: u.Scheme, Host: u.Host, Path: u.Path, RawQuery: u.RawQuery}).String()
+	default:
+		return nil, fmt.Errorf("unknown transport %q", u.Scheme)
+	}
+	if srv.tls, err = tlsConfig(u.Hostname(), u.Fragment); err != nil {
+		return nil, err
+	}
+	if srv.transport == TransportHTTPS {
+		srv.http = &http.Client{Transport: &http.Transport{
+			TLSClientConfig: srv.tls,
+			Proxy:           http.ProxyFromEnvironment,
+			IdleConnTimeout: httpIdleTimeout,
+		}}
+	}
+	return srv, nil
+}
+
+// Returns the TLS configuration for host with the options of the fragment
+// of a server address
+func tlsConfig(host, options string) (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: host}
+	var spki, certs []string
+	for _, o := range strings.Split(options, ",") {
+		if o == "" {
+			continue
+		}
+		kv := strings.SplitN(o, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid TLS option %q", o)
+		}
+		switch kv[0] {
+		case "name":
+			cfg.ServerName = kv[1]
+		case "pin-sha256":
+			spki = append(spki, kv[1])
+		case "cert-sha256":
+			certs = append(certs, strings.ToLower(kv[1]))
+		default:
+			return nil, fmt.Errorf("unknown TLS option %q", kv[0])
+		}
+	}
+	if len(spki)+len(certs) > 0 {
+		// The pins replace the verification against the system roots
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyPeerCertificate = func(raw [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPins(raw, spki, certs)
+		}
+	}
+	return cfg, nil
+}
+
+// Checks that the server certificate, the first of the chain, matches one
+// of the SPKI or certificate pins. Other certificates of the chain are not
+// considered as the server does not prove to own their keys.
+func verifyPins(chain [][]byte, spki, certs []string) error {
+	if len(chain) == 0 {
+		return errors.New("no server certificate")
+	}
+	cert, err := x509.ParseCertificate(chain[0])
+	if err != nil {
+		return err
+	}
+	d := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, p := range spki {
+		if p == base64.StdEncoding.EncodeToString(d[:]) {
+			return nil
+		}
+	}
+	d = sha256.Sum256(chain[0])
+	for _, p := range certs {
+		if p == hex.EncodeToString(d[:]) {
+			return nil
+		}
+	}
+	return errors.New("server certificate matches no pin")
+}
+
+// Sends m to the server over its transport. A truncated UDP response is
+// retried over TCP.
+func (s *server) exchange(ctx context.Context, m *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	if s.transport == TransportHTTPS {
+		return s.exchangeHTTPS(ctx, m, timeout)
+	}
+	c := &dns.Client{Net: s.transport, Timeout: timeout}
+	if s.transport == TransportTLS {
+		c.Net, c.TLSConfig = "tcp-tls", s.tls
+	}
+	r, _, err := c.ExchangeContext(ctx, m, s.addr)
+	if err == nil && r.Truncated && s.transport == TransportUDP {
+		Info.Printf("Truncated response from %s, retrying over TCP\n", s.addr)
+		c.Net = TransportTCP
+		if rt, _, err := c.ExchangeContext(ctx, m, s.addr); err == nil {
+			return rt, nil
+		}
+	}
+	return r, err
+}
+
+// Sends m as POST request by RFC 8484 section 4.1. The message ID is zero
+// on the wire to make responses cacheable.
+func (s *server) exchangeHTTPS(ctx context.Context, m *dns.Msg, timeout time.Duration) (*dns.Msg, error) {
+	q := m.Copy()
+	q.Id = 0
+	data, err := q.Pack()
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, s.addr, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", dohMediaType)
+	req.Header.Set("Accept", dohMediaType)
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	resp, err := s.http.Do(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: HTTP status %s", s.addr, resp.Status)
+	}
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, dns.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	r := new(dns.Msg)
+	if err := r.Unpack(body); err != nil {
+		return nil, err
+	}
+	r.Id = m.Id
+	return r, nil
+}
+
+// ServerView compares the DNSSEC RRsets a configured server returns with
+// those of the first server, e.g. to check that DNS over TLS and DNS over
+// HTTPS front-ends serve the same data as the UDP service
+type ServerView struct {
+	Server      string   `json:"server"`
+	Consistent  bool     `json:"consistent"`
+	Differences []string `json:"differences,omitempty"`
+	Errors      []string `json:"errors,omitempty"`
+}
+
+// Queries the DNSKEY and DS RRsets and their RRSIGs of every zone of the
+// result from each server. The queries are not answered from the cache.
+func (in *Inspector) compareServers(ctx context.Context, res *Result, servers []string) []ServerView {
+	answers := make([]map[string]string, len(servers))
+	errs := make([][]string, len(servers))
+	for i, s := range servers {
+		answers[i] = map[string]string{}
+		srv := in.server(s)
+		for _, z := range res.Zones {
+			for _, t := range []uint16{dns.TypeDNSKEY, dns.TypeDS} {
+				if t == dns.TypeDS && z.FQDN == "." {
+					continue
+				}
+				key := z.FQDN + " " + dns.TypeToString[t]
+				m := new(dns.Msg)
+				m.SetQuestion(dns.Fqdn(z.FQDN), t)
+				m.SetEdns0(4096, true)
+				m.CheckingDisabled = true
+				r, err := in.exchange(ctx, srv, m)
+				if err != nil {
+					errs[i] = append(errs[i], fmt.Sprintf("%s: %s", key, err))
+					continue
+				}
+				var sigs []string
+				for _, sig := range getRRsigs(*r, t) {
+					sigs = append(sigs, rdataString(sig))
+				}
+				// Servers may return the RRs in any order
+				sort.Strings(sigs)
+				rrs := append(sortedRdata(*r, z.FQDN, t), sigs...)
+				answers[i][key] = dns.RcodeToString[r.Rcode] + " " + strings.Join(rrs, "\n")
+			}
+		}
+	}
+	return compareViews(servers, answers, errs)
+}
+
+// Compares the answers of each server with those of the first one. RRsets
+// a server failed to answer are not compared.
+func compareViews(servers []string, answers []map[string]string, errs [][]string) []ServerView {
+	ret := make([]ServerView, len(servers))
+	for i, s := range servers {
+		ret[i] = ServerView{Server: s, Consistent: true, Errors: errs[i]}
+		var keys []string
+		for k := range answers[i] {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if want, ok := answers[0][k]; ok && answers[i][k] != want {
+				ret[i].Consistent = false
+				ret[i].Differences = append(ret[i].Differences, fmt.Sprintf("%s differs from %s", k, servers[0]))
+			}
+		}
+	}
+	return ret
+}
@@ -0,0 +1,187 @@
+package inspector
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+func TestParseServer(t *testing.T) {
+	tests := []struct {
+		in, transport, addr string
+	}{
+		{"9.9.9.9", TransportUDP, "9.9.9.9:53"},
+		{"2001:db8::1", TransportUDP, "[2001:db8::1]:53"},
+		{"udp://9.9.9.9:5353", TransportUDP, "9.9.9.9:5353"},
+		{"tcp://9.9.9.9", TransportTCP, "9.9.9.9:53"},
+		{"tls://9.9.9.9#name=dns.quad9.net", TransportTLS, "9.9.9.9:853"},
+		{"tls://[2001:db8::1]:8853", TransportTLS, "[2001:db8::1]:8853"},
+		{"https://dns.quad9.net/dns-query#pin-sha256=AAAA", TransportHTTPS, "https://dns.quad9.net/dns-query"},
+	}
+	for _, tt := range tests {
+		s, err := parseServer(tt.in)
+		if err != nil {
+			t.Errorf("%s: %s", tt.in, err)
+			continue
+		}
+		if s.transport != tt.transport || s.addr != tt.addr {
+			t.Errorf("%s: got %s %s, want %s %s", tt.in, s.transport, s.addr, tt.transport, tt.addr)
+		}
+	}
+	s, _ := parseServer("tls://9.9.9.9#name=dns.quad9.net")
+	if s.tls.ServerName != "dns.quad9.net" || s.tls.InsecureSkipVerify {
+		t.Errorf("Unexpected TLS configuration %+v", s.tls)
+	}
+	r := &ServerResolver{}
+	a, _ := r.server("https://dns.quad9.net/dns-query")
+	b, _ := r.server("https://dns.quad9.net/dns-query")
+	if a.http == nil || a.http != b.http {
+		t.Error("HTTP client of a DNS over HTTPS server is not reused")
+	}
+	for _, in := range []string{"quic://9.9.9.9", "tls://9.9.9.9#pin=AAAA", "https://dns.example#name"} {
+		if _, err := parseServer(in); err == nil {
+			t.Errorf("%s: no error", in)
+		}
+	}
+}
+
+// Answers every question with an A RR
+func testAnswer(w dns.ResponseWriter, r *dns.Msg) {
+	m := new(dns.Msg)
+	m.SetReply(r)
+	rr, _ := dns.NewRR(r.Question[0].Name + " 300 IN A 192.0.2.1")
+	m.Answer = append(m.Answer, rr)
+	w.WriteMsg(m)
+}
+
+func TestServerResolverTransports(t *testing.T) {
+	doh := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		q := new(dns.Msg)
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != dohMediaType || q.Unpack(body) != nil || q.Id != 0 {
+			http.Error(w, "bad request", http.StatusBadRequest)
+			return
+		}
+		m := new(dns.Msg)
+		m.SetReply(q)
+		rr, _ := dns.NewRR(q.Question[0].Name + " 300 IN A 192.0.2.1")
+		m.Answer = append(m.Answer, rr)
+		data, _ := m.Pack()
+		w.Header().Set("Content-Type", dohMediaType)
+		w.Write(data)
+	}))
+	defer doh.Close()
+	cert := doh.TLS.Certificates[0].Certificate[0]
+	parsed, err := x509.ParseCertificate(cert)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spki := sha256.Sum256(parsed.RawSubjectPublicKeyInfo)
+	certPin := sha256.Sum256(cert)
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{Certificates: doh.TLS.Certificates})
+	if err != nil {
+		t.Skipf("Cannot listen on tcp: %s", err)
+	}
+	started := make(chan struct{})
+	dot := &dns.Server{Listener: l, Net: "tcp-tls", Handler: dns.HandlerFunc(testAnswer), NotifyStartedFunc: func() { close(started) }}
+	go dot.ActivateAndServe()
+	<-started
+	defer dot.Shutdown()
+
+	tl, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("Cannot listen on tcp: %s", err)
+	}
+	started = make(chan struct{})
+	tcp := &dns.Server{Listener: tl, Handler: dns.HandlerFunc(testAnswer), NotifyStartedFunc: func() { close(started) }}
+	go tcp.ActivateAndServe()
+	<-started
+	defer tcp.Shutdown()
+
+	tests := []struct {
+		server string
+		ok     bool
+	}{
+		{"tcp://" + tl.Addr().String(), true},
+		{"tls://" + l.Addr().String() + "#pin-sha256=" + base64.StdEncoding.EncodeToString(spki[:]), true},
+		{"tls://" + l.Addr().String() + "#cert-sha256=" + hex.EncodeToString(certPin[:]), true},
+		{"tls://" + l.Addr().String() + "#pin-sha256=" + base64.StdEncoding.EncodeToString(make([]byte, 32)), false},
+		// The test certificate is not signed by a system root
+		{"tls://" + l.Addr().String(), false},
+		{doh.URL + "/dns-query#pin-sha256=" + base64.StdEncoding.EncodeToString(spki[:]), true},
+		{doh.URL + "/dns-query#cert-sha256=" + hex.EncodeToString(make([]byte, 32)), false},
+	}
+	for _, tt := range tests {
+		s := &ServerResolver{Servers: []string{tt.server}}
+		m := new(dns.Msg)
+		m.SetQuestion("example.", dns.TypeA)
+		r, err := s.Exchange(context.Background(), m)
+		if tt.ok && (err != nil || len(r.Answer) != 1 || r.Id != m.Id) {
+			t.Errorf("%s: got %v (%v)", tt.server, r, err)
+		}
+		if !tt.ok && err == nil {
+			t.Errorf("%s: no error", tt.server)
+		}
+	}
+}
+
+func TestCompareViews(t *testing.T) {
+	servers := []string{"9.9.9.9", "tls://9.9.9.9", "https://dns.quad9.net/dns-query"}
+	answers := []map[string]string{
+		{"example DNSKEY": "NOERROR 257 3 13 AA==", "example DS": "NOERROR 1 13 2 AA"},
+		{"example DNSKEY": "NOERROR 257 3 13 AA==", "example DS": "NOERROR 1 13 2 AA"},
+		{"example DNSKEY": "NOERROR 257 3 13 BB==", "example DS": "NOERROR 1 13 2 AA"},
+	}
+	views := compareViews(servers, answers, make([][]string, 3))
+	if !views[0].Consistent || !views[1].Consistent || views[2].Consistent {
+		t.Errorf("Unexpected views %+v", views)
+	}
+	want := "[example DNSKEY differs from 9.9.9.9]"
+	if fmt.Sprint(views[2].Differences) != want {
+		t.Errorf("Got differences %q, want %s", views[2].Differences, want)
+	}
+}
+
+func TestTruncatedRetry(t *testing.T) {
+	handler := func(w dns.ResponseWriter, r *dns.Msg) {
+		m := new(dns.Msg)
+		m.SetReply(r)
+		if _, ok := w.RemoteAddr().(*net.UDPAddr); ok {
+			m.Truncated = true
+		} else {
+			testAnswer(w, r)
+			return
+		}
+		w.WriteMsg(m)
+	}
+	addr, s := startTestServer(t, handler)
+	defer s.Shutdown()
+	tl, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Skipf("Cannot listen on tcp: %s", err)
+	}
+	started := make(chan struct{})
+	tcp := &dns.Server{Listener: tl, Handler: dns.HandlerFunc(handler), NotifyStartedFunc: func() { close(started) }}
+	go tcp.ActivateAndServe()
+	<-started
+	defer tcp.Shutdown()
+
+	m := new(dns.Msg)
+	m.SetQuestion("example.", dns.TypeDNSKEY)
+	r, err := (&ServerResolver{Servers: []string{addr}}).Exchange(context.Background(), m)
+	if err != nil || r.Truncated || len(r.Answer) != 1 {
+		t.Errorf("Truncated response not retried over TCP: %v (%v)", r, err)
+	}
+}
@@ -0,0 +1,157 @@
+package inspector
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/miekg/dns"
+)
+
+// RootAnchors are the DS RRs of the root KSKs published by IANA in
+// https://data.iana.org/root-anchors/root-anchors.xml (KSK-2017 and KSK-2024).
+// They are used if no other trust anchors are configured.
+var RootAnchors = []string{
+	". IN DS 20326 8 2 E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D",
+	". IN DS 38696 8 2 683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16",
+}
+
+// Layout of the IANA root-anchors.xml file
+type xmlTrustAnchor struct {
+	Zone       string `xml:"Zone"`
+	KeyDigests []struct {
+		ValidFrom  string `xml:"validFrom,attr"`
+		ValidUntil string `xml:"validUntil,attr"`
+		KeyTag     uint16 `xml:"KeyTag"`
+		Algorithm  uint8  `xml:"Algorithm"`
+		DigestType uint8  `xml:"DigestType"`
+		Digest     string `xml:"Digest"`
+	} `xml:"KeyDigest"`
+}
+
+// LoadTrustAnchors reads trust anchors from a file. The file is either in
+// the XML format of the IANA root-anchors.xml or in zone file format
+// containing DS and/or DNSKEY RRs. Key digests of the XML file that are not
+// valid at the moment are skipped.
+func LoadTrustAnchors(path string) ([]dns.RR, error) {
+	return LoadTrustAnchorsAt(path, time.Time{})
+}
+
+// LoadTrustAnchorsAt is like LoadTrustAnchors but skips the key digests of
+// the XML file that are not valid at t, so the anchors match an audit at
+// Options.Time. Zero t uses the current time.
+func LoadTrustAnchorsAt(path string, t time.Time) ([]dns.RR, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if t.IsZero() {
+		t = time.Now()
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parseXMLTrustAnchors(data, t.UTC())
+	}
+	var ret []dns.RR
+	zp := dns.NewZoneParser(bytes.NewReader(data), ".", path)
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		switch rr.(type) {
+		case *dns.DS, *dns.DNSKEY:
+			ret = append(ret, rr)
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if len(ret) == 0 {
+		return nil, fmt.Errorf("no DS or DNSKEY RRs in %s", path)
+	}
+	return ret, nil
+}
+
+// Converts the key digests of a root-anchors.xml file valid at t into DS RRs
+func parseXMLTrustAnchors(data []byte, t time.Time) ([]dns.RR, error) {
+	var ta xmlTrustAnchor
+	if err := xml.Unmarshal(data, &ta); err != nil {
+		return nil, err
+	}
+	var ret []dns.RR
+	for _, kd := range ta.KeyDigests {
+		if from, err := time.Parse(time.RFC3339, kd.ValidFrom); err == nil && t.Before(from) {
+			continue
+		}
+		if until, err := time.Parse(time.RFC3339, kd.ValidUntil); err == nil && t.After(until) {
+			continue
+		}
+		ds := &dns.DS{
+			Hdr:        dns.RR_Header{Name: dns.Fqdn(ta.Zone), Rrtype: dns.TypeDS, Class: dns.ClassINET},
+			KeyTag:     kd.KeyTag,
+			Algorithm:  kd.Algorithm,
+			DigestType: kd.DigestType,
+			Digest:     strings.ToUpper(strings.TrimSpace(kd.Digest)),
+		}
+		ret = append(ret, ds)
+	}
+	if len(ret) == 0 {
+		return nil, errors.New("no valid key digest in trust anchor file")
+	}
+	return ret, nil
+}
+
+// Returns the built-in root trust anchors
+func rootAnchors() []dns.RR {
+	var ret []dns.RR
+	for _, s := range RootAnchors {
+		rr, err := dns.NewRR(s)
+		if err != nil {
+			Error.Printf("Cannot parse built-in trust anchor %q: %s\n", s, err)
+			continue
+		}
+		ret = append(ret, rr)
+	}
+	return ret
+}
+
+// Returns the configured trust anchors for the zone fqdn
+func (in *Inspector) anchorsFor(fqdn string) (ret []dns.RR) {
+	for _, rr := range in.anchors {
+		if equalNames(rr.Header().Name, fqdn) {
+			ret = append(ret, rr)
+		}
+	}
+	return
+}
+
+// Checks if the DNSKEY is matched by one of the trust anchors
+func isAnchored(key *dns.DNSKEY, anchors []dns.RR) bool {
+	for _, a := range anchors {
+		switch t := a.(type) {
+		case *dns.DS:
+			if dsMatchesKey(t, key) {
+				return true
+			}
+		case *dns.DNSKEY:
+			if t.Algorithm == key.Algorithm && t.PublicKey == key.PublicKey && t.Flags == key.Flags {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Checks if the DS RR is the digest of key
+func dsMatchesKey(ds *dns.DS, key *dns.DNSKEY) bool {
+	if ds.KeyTag != key.KeyTag() || ds.Algorithm != key.Algorithm {
+		return false
+	}
+	newDS := key.ToDS(ds.DigestType)
+	return newDS != nil && strings.EqualFold(newDS.Digest, ds.Digest)
+}
+
+// Compares two domain names case-insensitively and ignoring the trailing dot
+func equalNames(a, b string) bool {
+	return strings.EqualFold(dns.Fqdn(a), dns.Fqdn(b))
+}
@@ -0,0 +1,362 @@
+package inspector
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/miekg/dns"
+)
+
+// ZoneFileResult is the result of the offline verification of a signed
+// master file
+type ZoneFileResult struct {
+	Origin    string   `json:"origin"`
+	Valid     bool     `json:"valid"`
+	RRsets    int      `json:"rrsets"`
+	Signed    int      `json:"signed"`
+	Denial    string   `json:"denial,omitempty"`
+	NSEC3iter int      `json:"nsec3iter,omitempty"`
+	Keys      []Key    `json:"keys,omitempty"`
+	DS        []string `json:"ds,omitempty"`
+	// Signatures lists the RRSIGs with warnings only
+	Signatures []Signature `json:"signatures,omitempty"`
+	Errors     []string    `json:"errors,omitempty"`
+}
+
+// Key of an RRset in a zone file
+type rrsetKey struct {
+	name  string
+	rtype uint16
+}
+
+// A parsed zone file
+type zoneData struct {
+	apex    string
+	rrsets  map[rrsetKey][]dns.RR
+	sigs    map[rrsetKey][]*dns.RRSIG
+	names   map[string]bool
+	cuts    map[string]bool
+	nsec    []*dns.NSEC
+	nsec3   []*dns.NSEC3
+	keys    []*dns.DNSKEY
+	keySigs []*dns.RRSIG
+}
+
+// VerifyZoneFile checks a signed master file offline, like dnssec-verify:
+// every authoritative RRset has to be signed by a DNSKEY of the apex with a
+// valid signature, the NSEC or NSEC3 chain has to be complete and ordered and
+// the DNSKEYs are rated by the configured policies. The DS RRs for the KSKs
+// are returned to be published at the parent. origin is used for relative
+// names; the apex is the owner of the SOA RR.
+func (in *Inspector) VerifyZoneFile(r io.Reader, origin, filename string) (*ZoneFileResult, error) {
+	z, err := parseZoneFile(r, origin, filename)
+	if err != nil {
+		return nil, err
+	}
+	res := &ZoneFileResult{Origin: z.apex}
+	now := in.now()
+	if len(z.keys) == 0 {
+		res.Errors = append(res.Errors, "no DNSKEY RRs at the apex")
+	}
+
+	var ksks []*dns.DNSKEY
+	for _, k := range z.keys {
+		key := Key{}
+		checkKey(*k, &key, in.policies, now.Year())
+		// A KSK has to sign the DNSKEY RRset, a ZSK the SOA RRset
+		signed := keysToRRs(z.keys)
+		sigs := z.keySigs
+		if k.Flags&dns.SEP == 0 {
+			signed = z.rrsets[rrsetKey{z.apex, dns.TypeSOA}]
+			sigs = z.sigs[rrsetKey{z.apex, dns.TypeSOA}]
+		}
+		key.Verifiable = verifyRRset(signed, sigs, []*dns.DNSKEY{k}, now) == nil
+		res.Keys = append(res.Keys, key)
+		if k.Flags&dns.SEP != 0 && k.Flags&dns.REVOKE == 0 {
+			ksks = append(ksks, k)
+		}
+	}
+	res.DS = dsRecords(ksks)
+
+	var all []*dns.RRSIG
+	for _, k := range z.sortedRRsets() {
+		res.RRsets++
+		if !z.authoritative(k) {
+			continue
+		}
+		sigs := z.sigs[k]
+		if len(sigs) == 0 {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s %s: not signed", k.name, dns.TypeToString[k.rtype]))
+			continue
+		}
+		res.Signed++
+		all = append(all, sigs...)
+		if err := verifyRRset(z.rrsets[k], sigs, z.keys, now); err != nil {
+			res.Errors = append(res.Errors, fmt.Sprintf("%s %s: %s", k.name, dns.TypeToString[k.rtype], err))
+		}
+	}
+	for _, s := range evaluateSignatures(all, now, in.opts.ExpiryWarning) {
+		if len(s.Warnings) > 0 {
+			res.Signatures = append(res.Signatures, s)
+		}
+	}
+
+	switch {
+	case len(z.nsec3) > 0:
+		res.Denial = "NSEC3"
+		res.NSEC3iter = int(z.nsec3[0].Iterations)
+		res.Errors = append(res.Errors, z.checkNSEC3Chain()...)
+	case len(z.nsec) > 0:
+		res.Denial = "NSEC"
+		res.Errors = append(res.Errors, z.checkNSECChain()...)
+	default:
+		res.Errors = append(res.Errors, "neither NSEC nor NSEC3 RRs in the zone")
+	}
+	res.Valid = len(res.Errors) == 0
+	return res, nil
+}
+
+func parseZoneFile(r io.Reader, origin, filename string) (*zoneData, error) {
+	z := &zoneData{
+		rrsets: map[rrsetKey][]dns.RR{},
+		sigs:   map[rrsetKey][]*dns.RRSIG{},
+		names:  map[string]bool{},
+		cuts:   map[string]bool{},
+	}
+	zp := dns.NewZoneParser(r, dns.Fqdn(origin), filename)
+	var rrs []dns.RR
+	for rr, ok := zp.Next(); ok; rr, ok = zp.Next() {
+		rr.Header().Name = strings.ToLower(rr.Header().Name)
+		rrs = append(rrs, rr)
+		if rr.Header().Rrtype == dns.TypeSOA {
+			if z.apex != "" && z.apex != rr.Header().Name {
+				return nil, errors.New("more than one SOA RR in the zone file")
+			}
+			z.apex = rr.Header().Name
+		}
+	}
+	if err := zp.Err(); err != nil {
+		return nil, err
+	}
+	if z.apex == "" {
+		return nil, errors.New("no SOA RR in the zone file")
+	}
+	for _, rr := range rrs {
+		name := rr.Header().Name
+		switch t := rr.(type) {
+		case *dns.RRSIG:
+			k := rrsetKey{name, t.TypeCovered}
+			z.sigs[k] = append(z.sigs[k], t)
+			continue
+		case *dns.NSEC3:
+			z.nsec3 = append(z.nsec3, t)
+		case *dns.NSEC:
+			z.nsec = append(z.nsec, t)
+		case *dns.DNSKEY:
+			if name == z.apex {
+				z.keys = append(z.keys, t)
+			}
+		case *dns.NS:
+			if name != z.apex {
+				z.cuts[name] = true
+			}
+		}
+		k := rrsetKey{name, rr.Header().Rrtype}
+		z.rrsets[k] = append(z.rrsets[k], rr)
+		if k.rtype != dns.TypeNSEC3 {
+			z.names[name] = true
+		}
+	}
+	z.keySigs = z.sigs[rrsetKey{z.apex, dns.TypeDNSKEY}]
+	return z, nil
+}
+
+// Returns the RRsets in canonical order of their owner names
+func (z *zoneData) sortedRRsets() []rrsetKey {
+	var ret []rrsetKey
+	for k := range z.rrsets {
+		ret = append(ret, k)
+	}
+	sort.Slice(ret, func(i, j int) bool {
+		if c := canonicalCompare(ret[i].name, ret[j].name); c != 0 {
+			return c < 0
+		}
+		return ret[i].rtype < ret[j].rtype
+	})
+	return ret
+}
+
+// Returns the zone cut name is at or below, or ""
+func (z *zoneData) cutOf(name string) string {
+	for cut := range z.cuts {
+		if dns.IsSubDomain(cut, name) {
+			return cut
+		}
+	}
+	return ""
+}
+
+// Checks if an RRset has to be signed: everything but the NS RRset at a
+// delegation point and glue below it (RFC 4035 section 2.2)
+func (z *zoneData) authoritative(k rrsetKey) bool {
+	cut := z.cutOf(k.name)
+	if cut == "" {
+		return true
+	}
+	return cut == k.name && (k.rtype == dns.TypeDS || k.rtype == dns.TypeNSEC)
+}
+
+// Returns the names of the zone in canonical order without glue. With
+// emptyNonTerminals the names between the apex and existing names are added.
+func (z *zoneData) sortedNames(emptyNonTerminals bool) []string {
+	names := map[string]bool{}
+	for n := range z.names {
+		if cut := z.cutOf(n); cut != "" && cut != n {
+			continue
+		}
+		names[n] = true
+		if !emptyNonTerminals {
+			continue
+		}
+		labels := dns.SplitDomainName(n)
+		for i := 1; i < len(labels); i++ {
+			parent := dns.Fqdn(strings.Join(labels[i:], "."))
+			if !dns.IsSubDomain(z.apex, parent) || parent == z.apex {
+				break
+			}
+			names[parent] = true
+		}
+	}
+	var ret []string
+	for n := range names {
+		ret = append(ret, n)
+	}
+	sort.Slice(ret, func(i, j int) bool { return canonicalCompare(ret[i], ret[j]) < 0 })
+	return ret
+}
+
+// Types expected in the NSEC/NSEC3 type bitmap of name. NSEC RRs are always
+// signed, for NSEC3 RRSIG is only expected if an RRset at the name is signed.
+func (z *zoneData) types(name string, denial uint16) []uint16 {
+	var ret []uint16
+	signed := denial == dns.TypeNSEC
+	for k := range z.rrsets {
+		if k.name != name || k.rtype == dns.TypeNSEC3 {
+			continue
+		}
+		ret = append(ret, k.rtype)
+		if z.authoritative(k) {
+			signed = true
+		}
+	}
+	if signed {
+		ret = append(ret, dns.TypeRRSIG)
+	}
+	sort.Slice(ret, func(i, j int) bool { return ret[i] < ret[j] })
+	return ret
+}
+
+// Checks that every name has an NSEC RR pointing to the next name in
+// canonical order, the last one to the apex, and that the type bitmaps match
+// the RRsets (RFC 4034 section 4.1)
+func (z *zoneData) checkNSECChain() (errs []string) {
+	nsec := map[string]*dns.NSEC{}
+	for _, n := range z.nsec {
+		nsec[n.Hdr.Name] = n
+	}
+	names := z.sortedNames(false)
+	for i, name := range names {
+		n, ok := nsec[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("NSEC chain: no NSEC RR for %s", name))
+			continue
+		}
+		next := names[(i+1)%len(names)]
+		if !equalNames(n.NextDomain, next) {
+			errs = append(errs, fmt.Sprintf("NSEC chain: %s points to %s instead of %s", name, n.NextDomain, next))
+		}
+		if err := compareBitmap(n.TypeBitMap, z.types(name, dns.TypeNSEC)); err != nil {
+			errs = append(errs, fmt.Sprintf("NSEC %s: %s", name, err))
+		}
+	}
+	return
+}
+
+// Checks that every name, including empty non-terminals, has an NSEC3 RR
+// with the parameters of the NSEC3PARAM RR and that the NSEC3 RRs form a
+// closed chain in hash order (RFC 5155 section 7.1). Insecure delegations
+// may be left out in opt-out zones.
+func (z *zoneData) checkNSEC3Chain() (errs []string) {
+	params := z.nsec3[0]
+	if p, ok := z.rrsets[rrsetKey{z.apex, dns.TypeNSEC3PARAM}]; ok {
+		param := p[0].(*dns.NSEC3PARAM)
+		params = &dns.NSEC3{Hash: param.Hash, Iterations: param.Iterations, Salt: param.Salt}
+	} else {
+		errs = append(errs, "no NSEC3PARAM RR at the apex")
+	}
+	nsec3 := map[string]*dns.NSEC3{}
+	var hashes []string
+	optOut := false
+	for _, n := range z.nsec3 {
+		labels := dns.SplitDomainName(n.Hdr.Name)
+		if len(labels) == 0 || !equalNames(parentName(n.Hdr.Name), z.apex) {
+			errs = append(errs, fmt.Sprintf("NSEC3 %s: owner is not a hash directly below the apex", n.Hdr.Name))
+			continue
+		}
+		h := strings.ToUpper(labels[0])
+		if n.Hash != params.Hash || n.Iterations != params.Iterations || !strings.EqualFold(n.Salt, params.Salt) {
+			errs = append(errs, fmt.Sprintf("NSEC3 %s: parameters differ from NSEC3PARAM", n.Hdr.Name))
+		}
+		if n.Flags&1 != 0 {
+			optOut = true
+		}
+		nsec3[h] = n
+		hashes = append(hashes, h)
+	}
+	sort.Strings(hashes)
+	for i, h := range hashes {
+		next := hashes[(i+1)%len(hashes)]
+		if !strings.EqualFold(nsec3[h].NextDomain, next) {
+			errs = append(errs, fmt.Sprintf("NSEC3 chain: %s points to %s instead of %s", h, nsec3[h].NextDomain, next))
+		}
+	}
+	for _, name := range z.sortedNames(true) {
+		h := dns.HashName(name, params.Hash, params.Iterations, params.Salt)
+		n, ok := nsec3[h]
+		if !ok {
+			if optOut && z.cuts[name] && len(z.rrsets[rrsetKey{name, dns.TypeDS}]) == 0 {
+				continue
+			}
+			if optOut && !z.names[name] {
+				// empty non-terminal above opt-out delegations
+				continue
+			}
+			errs = append(errs, fmt.Sprintf("NSEC3 chain: no NSEC3 RR for %s (%s)", name, h))
+			continue
+		}
+		if err := compareBitmap(n.TypeBitMap, z.types(name, dns.TypeNSEC3)); err != nil {
+			errs = append(errs, fmt.Sprintf("NSEC3 %s (%s): %s", name, h, err))
+		}
+	}
+	return
+}
+
+func compareBitmap(bitmap, want []uint16) error {
+	got := append([]uint16{}, bitmap...)
+	sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+	if fmt.Sprint(got) == fmt.Sprint(want) {
+		return nil
+	}
+	return fmt.Errorf("type bitmap %s does not match the RRsets %s", typeNames(got), typeNames(want))
+}
+
+func typeNames(types []uint16) string {
+	var ret []string
+	for _, t := range types {
+		ret = append(ret, dns.TypeToString[t])
+	}
+	return strings.Join(ret, " ")
+}
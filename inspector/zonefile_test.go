@@ -0,0 +1,151 @@
+package inspector
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/miekg/dns"
+)
+
+var testZoneRecords = []string{
+	"example. 3600 IN SOA ns1.example. hostmaster.example. 1 7200 3600 1209600 3600",
+	"example. 3600 IN NS ns1.example.",
+	"ns1.example. 3600 IN A 192.0.2.1",
+	"www.example. 3600 IN A 192.0.2.2",
+	"a.b.example. 3600 IN A 192.0.2.3",
+	"sub.example. 3600 IN NS ns.sub.example.",
+	"ns.sub.example. 3600 IN A 192.0.2.4",
+}
+
+// Signs all RRsets of records with the key of z except the delegation NS
+// RRset, glue and the RRsets named in unsigned ("name type"), and returns
+// the zone file
+func signedZoneFile(t *testing.T, z *testZone, records []string, unsigned ...string) string {
+	rrs := append(mustRRs(t, records...), z.key)
+	var order []rrsetKey
+	sets := map[rrsetKey][]dns.RR{}
+	for _, rr := range rrs {
+		k := rrsetKey{rr.Header().Name, rr.Header().Rrtype}
+		if _, ok := sets[k]; !ok {
+			order = append(order, k)
+		}
+		sets[k] = append(sets[k], rr)
+	}
+	skip := map[string]bool{"sub.example. NS": true, "ns.sub.example. A": true}
+	for _, u := range unsigned {
+		skip[u] = true
+	}
+	var b strings.Builder
+	for _, k := range order {
+		for _, rr := range sets[k] {
+			fmt.Fprintln(&b, rr.String())
+		}
+		if !skip[k.name+" "+dns.TypeToString[k.rtype]] {
+			fmt.Fprintln(&b, z.sign(t, sets[k]...).String())
+		}
+	}
+	return b.String()
+}
+
+func TestVerifyZoneFileNSEC(t *testing.T) {
+	z := newTestZone(t, "example.")
+	nsec := []string{
+		"example. 3600 IN NSEC a.b.example. NS SOA RRSIG NSEC DNSKEY",
+		"a.b.example. 3600 IN NSEC ns1.example. A RRSIG NSEC",
+		"ns1.example. 3600 IN NSEC sub.example. A RRSIG NSEC",
+		"sub.example. 3600 IN NSEC www.example. NS RRSIG NSEC",
+		"www.example. 3600 IN NSEC example. A RRSIG NSEC",
+	}
+	in := New(Options{Resolver: &ServerResolver{}})
+
+	tests := []struct {
+		name     string
+		records  []string
+		unsigned []string
+		errors   []string
+	}{
+		{"valid", append(testZoneRecords, nsec...), nil, nil},
+		{"missing NSEC", append(testZoneRecords, nsec[0], nsec[1], nsec[3], nsec[4]), nil,
+			[]string{"NSEC chain: no NSEC RR for ns1.example."}},
+		{"unsigned", append(testZoneRecords, nsec...), []string{"www.example. A"},
+			[]string{"www.example. A: not signed"}},
+		{"bitmap", append(append([]string{}, testZoneRecords...), append(nsec[:4:4], "www.example. 3600 IN NSEC example. A AAAA RRSIG NSEC")...), nil,
+			[]string{"NSEC www.example.: type bitmap A AAAA RRSIG NSEC does not match the RRsets A RRSIG NSEC"}},
+	}
+	for _, tt := range tests {
+		data := signedZoneFile(t, z, tt.records, tt.unsigned...)
+		res, err := in.VerifyZoneFile(strings.NewReader(data), "example.", "test")
+		if err != nil {
+			t.Fatalf("%s: %s", tt.name, err)
+		}
+		if fmt.Sprint(res.Errors) != fmt.Sprint(tt.errors) || res.Valid != (len(tt.errors) == 0) {
+			t.Errorf("%s: got errors %q, want %q", tt.name, res.Errors, tt.errors)
+		}
+		if res.Denial != "NSEC" || len(res.Keys) != 1 || !res.Keys[0].Verifiable || len(res.DS) != 2 {
+			t.Errorf("%s: got %+v", tt.name, res)
+		}
+	}
+}
+
+func TestVerifyZoneFileNSEC3(t *testing.T) {
+	z := newTestZone(t, "example.")
+	types := map[string]string{
+		"example.":     "NS SOA RRSIG DNSKEY NSEC3PARAM",
+		"b.example.":   "", // empty non-terminal
+		"a.b.example.": "A RRSIG",
+		"ns1.example.": "A RRSIG",
+		"sub.example.": "NS",
+		"www.example.": "A RRSIG",
+	}
+	var hashes []string
+	owners := map[string]string{}
+	for name := range types {
+		h := dns.HashName(name, dns.SHA1, 0, "")
+		hashes = append(hashes, h)
+		owners[h] = name
+	}
+	sort.Strings(hashes)
+	records := append([]string{"example. 0 IN NSEC3PARAM 1 0 0 -"}, testZoneRecords...)
+	for i, h := range hashes {
+		records = append(records, fmt.Sprintf("%s.example. 3600 IN NSEC3 1 0 0 - %s %s",
+			h, hashes[(i+1)%len(hashes)], types[owners[h]]))
+	}
+
+	in := New(Options{Resolver: &ServerResolver{}})
+	res, err := in.VerifyZoneFile(strings.NewReader(signedZoneFile(t, z, records)), "example.", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Valid || res.Denial != "NSEC3" {
+		t.Errorf("got %+v", res)
+	}
+
+	// Without the NSEC3 RR of the empty non-terminal the chain is broken
+	var broken []string
+	for _, r := range records {
+		if !strings.HasPrefix(r, dns.HashName("b.example.", dns.SHA1, 0, "")) {
+			broken = append(broken, r)
+		}
+	}
+	res, err = in.VerifyZoneFile(strings.NewReader(signedZoneFile(t, z, broken)), "example.", "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Valid || len(res.Errors) != 2 {
+		t.Errorf("broken chain: got errors %q", res.Errors)
+	}
+
+	// Malformed NSEC3 owners are reported instead of crashing the check
+	for _, owner := range []string{".", "deep." + hashes[0] + ".example."} {
+		malformed := append(records, owner+" 3600 IN NSEC3 1 0 0 - "+hashes[0]+" A")
+		res, err = in.VerifyZoneFile(strings.NewReader(signedZoneFile(t, z, malformed)), "example.", "test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Valid || !strings.Contains(fmt.Sprint(res.Errors), "NSEC3 "+strings.ToLower(owner)+": owner is not a hash directly below the apex") {
+			t.Errorf("malformed owner %s: got errors %q", owner, res.Errors)
+		}
+	}
+}